@@ -4,7 +4,9 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 )
@@ -12,9 +14,53 @@ import (
 // Config is the representation of configuration settings.
 // The public interface is entirely through methods.
 type Config struct {
-	data map[string]map[string]string // Maps sections to options to values.
+	data        map[string]map[string]string   // Maps sections to options to values.
+	subsections map[string]bool                // Sections added with a case-preserved subsection name.
+	sections    []string                       // Sections in insertion order.
+	options     map[string][]string            // Maps sections to options in insertion order.
+	comments    map[string]map[string]string   // Comments of sections ("" option) and options.
+	pos         map[string]map[string]Position // Where sections ("" option) and options were read.
+	names       map[string]map[string]string   // Original spelling of sections ("" option) and options.
+	caseMode    CaseMode                       // How section and option names are compared and stored.
+	values      map[string]map[string][]string // All values of options with several values.
+	multi       bool                           // Read accumulates the values of repeated options.
+	flags       map[string]map[string]bool     // Options without value.
+	noValue     bool                           // Read accepts options without value.
+	secrets     map[string]map[string]bool     // Options explicitly marked secret or not.
+	patterns    []string                       // Patterns of secret option names.
+	transform   []ValueTransformer             // Applied in turn to values by String.
+	maxSize     int64                          // Maximum size read by Read (0 for no limit).
+	list        ListOptions                    // How list values are split.
+	bools       map[string]bool                // Strings accepted as bool.
+	strict      bool                           // Only accept "true" and "false" as bool.
+	root        *Config                        // Configuration viewed by Sub (nil if not a view).
+	prefix      string                         // Section viewed by Sub as the default section.
 }
 
+// ListOptions controls how StringList and the other list getters split a
+// value into items.
+type ListOptions struct {
+	Separator rune // Item separator within a line (',' if zero).
+	Brackets  bool // Accept lists enclosed in brackets, e.g. [a, b].
+	SkipEmpty bool // Drop empty items.
+}
+
+func (o ListOptions) separator() rune {
+	if o.Separator == 0 {
+		return ','
+	}
+	return o.Separator
+}
+
+// CaseMode defines how section and option names are compared and stored.
+type CaseMode int
+
+const (
+	CaseInsensitive CaseMode = iota // Names are compared and stored in lower case (default).
+	CasePreserving                  // Names are compared case insensitively but keep their spelling.
+	CaseSensitive                   // Names are compared and stored as they are.
+)
+
 const (
 	// Get Errors
 	SectionNotFound = iota
@@ -26,13 +72,17 @@ const (
 
 	// Get and Read Errors
 	CouldNotParse
+
+	// Get Errors
+	CouldNotTransform
 )
 
 var (
 	DefaultSection = "default" // Default section name (must be lower-case).
 	DepthValues    = 200       // Maximum allowed depth when recursively substituing variable names.
 
-	// Strings accepted as bool.
+	// Strings accepted as bool. New configurations start with a copy of
+	// this map, see Config.SetBoolStrings to change it per configuration.
 	BoolStrings = map[string]bool{
 		"t":     true,
 		"true":  true,
@@ -48,37 +98,286 @@ var (
 		"0":     false,
 	}
 
+	// Patterns (as in path.Match) of the names of secret options. New
+	// configurations start with a copy, see Config.SetSecretPatterns.
+	SecretPatterns = []string{"*password*", "*passwd*", "*secret*", "*token*", "*apikey*", "*api_key*", "*private_key*"}
+
+	// Errors matching the reasons of GetError and ReadError with errors.Is.
+	ErrSectionNotFound   = errors.New("conf: section not found")
+	ErrOptionNotFound    = errors.New("conf: option not found")
+	ErrMaxDepthReached   = errors.New("conf: max depth reached while unfolding variables")
+	ErrBlankSection      = errors.New("conf: empty section name")
+	ErrCouldNotParse     = errors.New("conf: could not parse")
+	ErrCouldNotTransform = errors.New("conf: could not transform value")
+
+	// ErrTooLarge is returned for input larger than the maximum size (see
+	// SetMaxSize, ReadFileLimit, LoadLimit and LimitReader).
+	ErrTooLarge = errors.New("conf: configuration too large")
+
 	varRegExp = regexp.MustCompile(`%\(([a-zA-Z0-9_.\-]+)\)s`)
 )
 
 // AddSection adds a new section to the configuration.
 // It returns true if the new section was inserted, and false if the section already existed.
 func (c *Config) AddSection(section string) bool {
-	section = strings.ToLower(section)
+	key := c.sectionKey(section)
+	if c.root != nil && key != c.prefix {
+		section = c.root.sectionName(c.prefix) + "." + section // spelling in c.root
+	}
+
+	return c.addSection(key, section)
+}
 
-	if _, ok := c.data[section]; ok {
+// addSection adds the section stored under key, spelled name.
+func (c *Config) addSection(key string, name string) bool {
+	if _, ok := c.data[key]; ok {
 		return false
 	}
-	c.data[section] = make(map[string]string)
+	c.data[key] = make(map[string]string)
+	r := c.rootConfig()
+	r.sections = append(r.sections, key)
+	if c.caseMode == CasePreserving && name != key {
+		c.setName(key, "", name)
+	}
 
 	return true
 }
 
+// AddSubsection adds a new subsection of section to the configuration, as
+// read from a [section "subsection"] line. The subsection name is case
+// sensitive: the section is then accessed as "section.subsection" with the
+// exact case of the subsection name.
+// It returns true if the new subsection was inserted, and false if it already existed.
+func (c *Config) AddSubsection(section string, subsection string) bool {
+	key := c.sectionKey(section) + "." + subsection
+	c.subsections[key] = true
+
+	return c.addSection(key, section+"."+subsection)
+}
+
+// Sub returns a view of the given section and its subsections: in the view,
+// the section is the default section and its subsection "section.name" is
+// the section "name". The view shares its data with c, so changes made
+// through either are visible in both. It starts with a copy of the settings
+// of c (list options, bool strings, transformers, etc.): settings changed
+// afterwards on c do not apply to the view, and vice versa.
+func (c *Config) Sub(section string) *Config {
+	v := *c
+	v.root = c.rootConfig()
+	v.prefix = c.sectionKey(section)
+	v.bools = copyBoolMap(c.bools)
+
+	return &v
+}
+
+// rootConfig returns the configuration viewed by c, or c if it is not a view.
+func (c *Config) rootConfig() *Config {
+	if c.root != nil {
+		return c.root
+	}
+	return c
+}
+
+// Clone returns a deep copy of the configuration, with the same settings.
+// The clone of a view is a view of a copy of the whole configuration.
+func (c *Config) Clone() *Config {
+	r := c.rootConfig()
+	clone := *r
+	clone.data = copyStringMaps(r.data)
+	clone.subsections = copyBoolMap(r.subsections)
+	clone.sections = append([]string(nil), r.sections...)
+	clone.options = make(map[string][]string, len(r.options))
+	for section, options := range r.options {
+		clone.options[section] = append([]string(nil), options...)
+	}
+	clone.comments = copyStringMaps(r.comments)
+	clone.pos = make(map[string]map[string]Position, len(r.pos))
+	for section, m := range r.pos {
+		clone.pos[section] = make(map[string]Position, len(m))
+		for option, pos := range m {
+			clone.pos[section][option] = pos
+		}
+	}
+	clone.names = copyStringMaps(r.names)
+	clone.values = make(map[string]map[string][]string, len(r.values))
+	for section, m := range r.values {
+		clone.values[section] = make(map[string][]string, len(m))
+		for option, values := range m {
+			clone.values[section][option] = append([]string(nil), values...)
+		}
+	}
+	clone.flags = make(map[string]map[string]bool, len(r.flags))
+	for section, m := range r.flags {
+		clone.flags[section] = copyBoolMap(m)
+	}
+	clone.secrets = make(map[string]map[string]bool, len(r.secrets))
+	for section, m := range r.secrets {
+		clone.secrets[section] = copyBoolMap(m)
+	}
+	clone.patterns = append([]string(nil), r.patterns...)
+	clone.transform = append([]ValueTransformer(nil), r.transform...)
+	clone.bools = copyBoolMap(r.bools)
+
+	if c.root != nil {
+		return clone.Sub(c.prefix)
+	}
+	return &clone
+}
+
+func copyStringMaps(maps map[string]map[string]string) map[string]map[string]string {
+	clone := make(map[string]map[string]string, len(maps))
+	for section, m := range maps {
+		clone[section] = make(map[string]string, len(m))
+		for option, s := range m {
+			clone[section][option] = s
+		}
+	}
+	return clone
+}
+
+func copyBoolMap(m map[string]bool) map[string]bool {
+	clone := make(map[string]bool, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// sectionKeys returns the keys in c.data of the sections of the configuration
+// (or view), in insertion order.
+func (c *Config) sectionKeys() []string {
+	if c.root == nil {
+		return c.sections
+	}
+
+	var keys []string
+	for _, key := range c.root.sections {
+		if key == c.prefix || strings.HasPrefix(key, c.prefix+".") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// sectionName returns the name of the section stored under key, with its
+// original spelling in CasePreserving mode, and relative to the section
+// viewed if c is a view.
+func (c *Config) sectionName(key string) string {
+	name := key
+	if n, ok := c.names[key][""]; ok {
+		name = n
+	}
+
+	switch {
+	case c.root == nil:
+		return name
+	case key == c.prefix:
+		return DefaultSection
+	}
+	return trimSegments(name, strings.Count(c.prefix, ".")+1)
+}
+
+// optionName returns the name of the option stored under key in the
+// section stored under section, with its original spelling in
+// CasePreserving mode.
+func (c *Config) optionName(section string, key string) string {
+	if n, ok := c.names[section][key]; ok {
+		return n
+	}
+	return key
+}
+
+// setName records the original spelling of a section ("" option) or option.
+func (c *Config) setName(section string, option string, name string) {
+	if _, ok := c.names[section]; !ok {
+		c.names[section] = make(map[string]string)
+	}
+	c.names[section][option] = name
+}
+
+// trimSegments removes the first n dot-separated segments of name.
+func trimSegments(name string, n int) string {
+	parts := strings.SplitN(name, ".", n+1)
+	return parts[len(parts)-1]
+}
+
+// parentKey returns the key of the parent of the section stored under key
+// ("a.b" for "a.b.c"), or "" if the section has no parent.
+// The parent of a subsection added with AddSubsection is its section.
+func (c *Config) parentKey(key string) string {
+	if c.subsections[key] {
+		return key[:strings.IndexByte(key, '.')]
+	}
+	if i := strings.LastIndexByte(key, '.'); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// sectionKey returns the key of the given section in c.data. The empty
+// section name is the default section. Section names are case insensitive,
+// except for the subsection names of sections added with AddSubsection.
+func (c *Config) sectionKey(section string) string {
+	if c.root != nil {
+		if section == "" || strings.ToLower(section) == DefaultSection {
+			return c.prefix
+		}
+		return c.root.sectionKey(c.prefix + "." + section)
+	}
+
+	if section == "" {
+		return DefaultSection
+	}
+	if c.caseMode == CaseSensitive {
+		return section
+	}
+	if i := strings.IndexByte(section, '.'); i > 0 {
+		if key := strings.ToLower(section[:i]) + section[i:]; c.subsections[key] {
+			return key
+		}
+	}
+	return strings.ToLower(section)
+}
+
+// optionKey returns the key of the given option in the maps of a section.
+func (c *Config) optionKey(option string) string {
+	if c.caseMode == CaseSensitive {
+		return option
+	}
+	return strings.ToLower(option)
+}
+
+// SetCaseMode sets how section and option names are compared and stored.
+// It should be called before any section or option is added, e.g. before
+// Read.
+func (c *Config) SetCaseMode(mode CaseMode) {
+	c.caseMode = mode
+}
+
 // RemoveSection removes a section from the configuration.
 // It returns true if the section was removed, and false if section did not exist.
 func (c *Config) RemoveSection(section string) bool {
-	section = strings.ToLower(section)
+	section = c.sectionKey(section)
 
 	switch _, ok := c.data[section]; {
 	case !ok:
 		return false
-	case section == DefaultSection:
+	case section == c.sectionKey(DefaultSection):
 		return false // default section cannot be removed
 	default:
 		for o, _ := range c.data[section] {
 			delete(c.data[section], o)
 		}
 		delete(c.data, section)
+		delete(c.options, section)
+		delete(c.comments, section)
+		delete(c.pos, section)
+		delete(c.names, section)
+		delete(c.values, section)
+		delete(c.flags, section)
+		delete(c.subsections, section)
+		r := c.rootConfig()
+		r.sections = removeName(r.sections, section)
 	}
 
 	return true
@@ -86,25 +385,105 @@ func (c *Config) RemoveSection(section string) bool {
 
 // AddOption adds a new option and value to the configuration.
 // It returns true if the option and value were inserted, and false if the value was overwritten.
+// All values of a multi-valued option are overwritten (see AppendOption).
 // If the section does not exist in advance, it is created.
 func (c *Config) AddOption(section string, option string, value string) bool {
 	c.AddSection(section) // make sure section exists
 
-	section = strings.ToLower(section)
-	option = strings.ToLower(option)
+	section = c.sectionKey(section)
+	name := option
+	option = c.optionKey(option)
 
 	_, ok := c.data[section][option]
 	c.data[section][option] = value
+	delete(c.values[section], option)
+	delete(c.flags[section], option)
+	if !ok {
+		c.options[section] = append(c.options[section], option)
+		if c.caseMode == CasePreserving && name != option {
+			c.setName(section, option, name)
+		}
+	}
 
 	return !ok
 }
 
+// AppendOption adds another value to an option, making it multi-valued.
+// Values returns all values of the option, while String and the other
+// getters return the last one. Write writes the option once per value.
+// It returns true if the option was inserted, and false if the value was appended.
+// If the section does not exist in advance, it is created.
+func (c *Config) AppendOption(section string, option string, value string) bool {
+	key := c.sectionKey(section)
+	opt := c.optionKey(option)
+
+	prev, ok := c.data[key][opt]
+	if !ok {
+		return c.AddOption(section, option, value)
+	}
+
+	values, multi := c.values[key][opt]
+	if !multi {
+		values = []string{prev}
+	}
+	if _, ok := c.values[key]; !ok {
+		c.values[key] = make(map[string][]string)
+	}
+	c.values[key][opt] = append(values, value)
+	c.data[key][opt] = value
+	delete(c.flags[key], opt)
+
+	return false
+}
+
+// AddFlag adds a new option without value, like "skip-networking" in a
+// MySQL configuration file, to the configuration. Its value is the empty
+// string, IsFlag reports it as flag and Write writes it without "=".
+// It returns true if the option was inserted, and false if it was overwritten.
+// If the section does not exist in advance, it is created.
+func (c *Config) AddFlag(section string, option string) bool {
+	ok := c.AddOption(section, option, "")
+
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
+	if _, ok := c.flags[section]; !ok {
+		c.flags[section] = make(map[string]bool)
+	}
+	c.flags[section][option] = true
+
+	return ok
+}
+
+// SetAllowNoValue sets whether Read accepts options without value, adding
+// them as flags (see AddFlag). Continuation lines of multi-line values must
+// then be indented.
+func (c *Config) SetAllowNoValue(allow bool) {
+	c.noValue = allow
+}
+
+// setLastValue replaces the last value of an option, keeping its other values.
+func (c *Config) setLastValue(section string, option string, value string) {
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
+
+	c.data[section][option] = value
+	if values, ok := c.values[section][option]; ok {
+		values[len(values)-1] = value
+	}
+}
+
+// SetMultiValue sets whether Read accumulates the values of options that
+// are repeated (see AppendOption) instead of keeping the last one.
+func (c *Config) SetMultiValue(multi bool) {
+	c.multi = multi
+}
+
 // RemoveOption removes a option and value from the configuration.
 // It returns true if the option and value were removed, and false otherwise,
 // including if the section did not exist.
 func (c *Config) RemoveOption(section string, option string) bool {
-	section = strings.ToLower(section)
-	option = strings.ToLower(option)
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
 
 	if _, ok := c.data[section]; !ok {
 		return false
@@ -112,28 +491,209 @@ func (c *Config) RemoveOption(section string, option string) bool {
 
 	_, ok := c.data[section][option]
 	delete(c.data[section], option)
+	delete(c.comments[section], option)
+	delete(c.pos[section], option)
+	delete(c.names[section], option)
+	delete(c.values[section], option)
+	delete(c.flags[section], option)
+	if ok {
+		c.options[section] = removeName(c.options[section], option)
+	}
 
 	return ok
 }
 
+// removeName returns names without name, reusing its storage.
+func removeName(names []string, name string) []string {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i], names[i+1:]...)
+		}
+	}
+	return names
+}
+
+// Comment returns the comment of the given option in the section, or of the
+// section itself if option is empty.
+func (c *Config) Comment(section string, option string) string {
+	return c.comments[c.sectionKey(section)][c.optionKey(option)]
+}
+
+// SetComment sets the comment written by Write above the given option in the
+// section, or above the section itself if option is empty. Comments may
+// span several lines. An empty comment removes it.
+func (c *Config) SetComment(section string, option string, comment string) {
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
+
+	if comment == "" {
+		delete(c.comments[section], option)
+		return
+	}
+	if _, ok := c.comments[section]; !ok {
+		c.comments[section] = make(map[string]string)
+	}
+	c.comments[section][option] = comment
+}
+
+// Position returns where the given option in the section was read, or where
+// the section itself was read if option is empty.
+// The zero Position is returned for sections and options that were not read
+// from a file or reader.
+func (c *Config) Position(section string, option string) Position {
+	return c.pos[c.sectionKey(section)][c.optionKey(option)]
+}
+
+// setPosition records where the given option (or section if option is empty) was read.
+func (c *Config) setPosition(section string, option string, pos Position) {
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
+
+	if _, ok := c.pos[section]; !ok {
+		c.pos[section] = make(map[string]Position)
+	}
+	c.pos[section][option] = pos
+}
+
+// ListOptions returns the list options of the configuration.
+func (c *Config) ListOptions() ListOptions {
+	return c.list
+}
+
+// SetListOptions sets how list values are split by StringList, IntList, etc.
+// Values spanning several lines always have one item per line.
+func (c *Config) SetListOptions(opts ListOptions) {
+	c.list = opts
+}
+
+// SetBoolStrings replaces the strings accepted as bool by Bool and BoolList.
+// The map is copied, and its keys are matched case insensitively.
+func (c *Config) SetBoolStrings(strs map[string]bool) {
+	c.bools = make(map[string]bool, len(strs))
+	for s, v := range strs {
+		c.bools[strings.ToLower(s)] = v
+	}
+}
+
+// AddBoolString adds a string accepted as bool by Bool and BoolList.
+func (c *Config) AddBoolString(s string, value bool) {
+	c.bools[strings.ToLower(s)] = value
+}
+
+// SetStrictBool sets whether Bool and BoolList only accept "true" and
+// "false" (in any case), ignoring the other bool strings.
+func (c *Config) SetStrictBool(strict bool) {
+	c.strict = strict
+}
+
+// SetMaxSize sets the maximum number of bytes Read and ReadContext accept
+// from a reader before failing with ErrTooLarge (0 for no limit), e.g. to
+// read configurations from untrusted sources.
+func (c *Config) SetMaxSize(n int64) {
+	c.maxSize = n
+}
+
+// ValueTransformer transforms the value of the given option in the section
+// of the configuration c, e.g. to decrypt it (see Decrypter). c is the
+// configuration being read, which may be a view (see Sub) or a clone of the
+// configuration the transformer was added to. It returns values it does not
+// apply to unchanged.
+type ValueTransformer func(c *Config, section string, option string, value string) (string, error)
+
+// SetTransformer sets the transformer applied by String, and so by the
+// typed and list getters, to every value (nil for none), replacing those
+// added with Use.
+func (c *Config) SetTransformer(t ValueTransformer) {
+	c.transform = nil
+	if t != nil {
+		c.transform = []ValueTransformer{t}
+	}
+}
+
+// Use adds a transformer to the pipeline applied by String to every value:
+// transformers are applied in the order they were added, each to the value
+// returned by the previous one, e.g. to resolve the secret references of
+// encrypted values:
+//
+//	c.Use(conf.Unfold)
+//	c.Use(conf.ExpandEnv)
+//	c.Use(conf.Decrypter(keys))
+//	c.Use(conf.ResolveSecrets(resolvers))
+func (c *Config) Use(t ValueTransformer) {
+	c.transform = append(c.transform[:len(c.transform):len(c.transform)], t)
+}
+
+// uses reports whether t, a function such as Unfold, is in the pipeline of
+// transformers of the configuration.
+func (c *Config) uses(t ValueTransformer) bool {
+	for _, u := range c.transform {
+		if reflect.ValueOf(u).Pointer() == reflect.ValueOf(t).Pointer() {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBool converts s to bool using the bool strings of the configuration.
+func (c *Config) parseBool(s string) (value bool, ok bool) {
+	s = strings.ToLower(s)
+	if c.strict {
+		return s == "true", s == "true" || s == "false"
+	}
+	value, ok = c.bools[s]
+	return value, ok
+}
+
 // New creates an empty configuration representation.
 // This representation can be filled with AddSection and AddOption and then
 // saved to a file using WriteFile.
 func New() *Config {
 	c := new(Config)
 	c.data = make(map[string]map[string]string)
+	c.subsections = make(map[string]bool)
+	c.options = make(map[string][]string)
+	c.comments = make(map[string]map[string]string)
+	c.pos = make(map[string]map[string]Position)
+	c.names = make(map[string]map[string]string)
+	c.values = make(map[string]map[string][]string)
+	c.flags = make(map[string]map[string]bool)
+	c.secrets = make(map[string]map[string]bool)
+	c.SetBoolStrings(BoolStrings)
+	c.SetSecretPatterns(SecretPatterns...)
 
 	c.AddSection(DefaultSection) // default section always exists
 
 	return c
 }
 
+// Position describes a location in a configuration file.
+type Position struct {
+	Filename string // Empty if the configuration was not read from a file.
+	Line     int    // Line number, starting at 1 (0 if unknown).
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	switch {
+	case !p.IsValid():
+		return "-"
+	case p.Filename == "":
+		return fmt.Sprintf("line %d", p.Line)
+	}
+	return fmt.Sprintf("%s:%d", p.Filename, p.Line)
+}
+
 type GetError struct {
 	Reason    int
 	ValueType string
 	Value     string
 	Section   string
 	Option    string
+	Err       error // Cause: the error of strconv or of the transformer, if any.
 }
 
 func (err GetError) Error() string {
@@ -146,11 +706,43 @@ func (err GetError) Error() string {
 		return fmt.Sprintf("could not parse %s value '%s'", string(err.ValueType), string(err.Value))
 	case MaxDepthReached:
 		return fmt.Sprintf("possible cycle while unfolding variables: max depth of %d reached", int(DepthValues))
+	case CouldNotTransform:
+		return fmt.Sprintf("could not transform value of option '%s' in section '%s': %v", string(err.Option), string(err.Section), err.Err)
 	}
 
 	return "invalid get error"
 }
 
+// Unwrap returns the cause of the error, if any.
+func (err GetError) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether target is the sentinel error of the reason, e.g.
+// ErrOptionNotFound for OptionNotFound.
+func (err GetError) Is(target error) bool {
+	return target != nil && target == reasonError(err.Reason)
+}
+
+// reasonError returns the sentinel error of reason, or nil.
+func reasonError(reason int) error {
+	switch reason {
+	case SectionNotFound:
+		return ErrSectionNotFound
+	case OptionNotFound:
+		return ErrOptionNotFound
+	case MaxDepthReached:
+		return ErrMaxDepthReached
+	case BlankSection:
+		return ErrBlankSection
+	case CouldNotParse:
+		return ErrCouldNotParse
+	case CouldNotTransform:
+		return ErrCouldNotTransform
+	}
+	return nil
+}
+
 type ReadError struct {
 	Reason int
 	Line   string
@@ -166,3 +758,9 @@ func (err ReadError) Error() string {
 
 	return "invalid read error"
 }
+
+// Is reports whether target is the sentinel error of the reason, e.g.
+// ErrCouldNotParse for CouldNotParse.
+func (err ReadError) Is(target error) bool {
+	return target != nil && target == reasonError(err.Reason)
+}
@@ -4,7 +4,11 @@
 package conf
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -32,6 +36,10 @@ list-4 = 1, 2, 3
 list-5 = 1.3, 2.0, 3
 
 list-6 = yes, true, no, 0, n
+
+[map]
+map-1 = env=prod, team=core
+map-3 = "a=1,2", b = 3
 `
 
 //url = http://%(host)s/something
@@ -46,6 +54,11 @@ type stringListTest struct {
 	option  string
 	answer  []string
 }
+type stringMapTest struct {
+	section string
+	option  string
+	answer  map[string]string
+}
 
 type intTest struct {
 	section string
@@ -104,6 +117,8 @@ var testSet = []interface{}{
 	boolTest{"default", "active", false},
 	boolListTest{"list", "list-6", []bool{true, true, false, false, false}},
 	intTest{"service-1", "port", 443},
+	stringMapTest{"map", "map-1", map[string]string{"env": "prod", "team": "core"}},
+	stringMapTest{"map", "map-3", map[string]string{"a": "1,2", "b": "3"}},
 	//stringTest{"service-1", "url", "http://example.com/something"},
 }
 
@@ -123,6 +138,10 @@ func TestBuild(t *testing.T) {
 			e := element.(stringListTest)
 			ans, err := c.StringList(e.section, e.option)
 			verifyList(t, testnum, "c.StringList", e.section, e.option, ans, e.answer, err)
+		case stringMapTest:
+			e := element.(stringMapTest)
+			ans, err := c.StringMap(e.section, e.option)
+			verifyList(t, testnum, "c.StringMap", e.section, e.option, ans, e.answer, err)
 		case intTest:
 			e := element.(intTest)
 			ans, err := c.Int(e.section, e.option)
@@ -178,3 +197,331 @@ func verifyList(t *testing.T, testnum int, testcase, section, option string, out
 		t.Fatalf(`%d. %s("%s", "%s"): output %v != %v`, testnum, testcase, section, option, output, expected)
 	}
 }
+
+func TestListOptions(t *testing.T) {
+	c := New()
+	c.AddOption("list", "path", "/usr/bin:/bin::/usr/local/bin")
+	c.AddOption("list", "words", "one  two three")
+	c.AddOption("list", "array", `["one,one", "two", three]`)
+	c.AddOption("list", "array-ml", "[\n one,\n two,\n]")
+
+	tests := []struct {
+		option string
+		opts   ListOptions
+		answer []string
+	}{
+		{"path", ListOptions{Separator: ':'}, []string{"/usr/bin", "/bin", "", "/usr/local/bin"}},
+		{"path", ListOptions{Separator: ':', SkipEmpty: true}, []string{"/usr/bin", "/bin", "/usr/local/bin"}},
+		{"words", ListOptions{Separator: ' '}, []string{"one", "two", "three"}},
+		{"array", ListOptions{Brackets: true}, []string{"one,one", "two", "three"}},
+		{"array-ml", ListOptions{Brackets: true}, []string{"one", "two"}},
+	}
+
+	for testnum, e := range tests {
+		ans, err := c.StringListOpts("list", e.option, e.opts)
+		verifyList(t, testnum, "c.StringListOpts", "list", e.option, ans, e.answer, err)
+	}
+
+	c.SetListOptions(ListOptions{Separator: ':', SkipEmpty: true})
+	ans, err := c.StringList("list", "path")
+	verifyList(t, 0, "c.StringList", "list", "path", ans, []string{"/usr/bin", "/bin", "/usr/local/bin"}, err)
+}
+
+func TestBoolStrings(t *testing.T) {
+	c := New()
+	c.AddOption("default", "feature", "Enabled")
+	c.AddOption("default", "debug", "yes")
+	c.AddOption("default", "strict", "TRUE")
+
+	if _, err := c.Bool("", "feature"); err == nil {
+		t.Fatal(`c.Bool("", "feature") accepted unknown bool string`)
+	}
+
+	c.AddBoolString("enabled", true)
+	ans, err := c.Bool("", "feature")
+	verify(t, 0, "c.Bool", "", "feature", ans, true, err)
+
+	other := New()
+	other.AddOption("default", "feature", "enabled")
+	if _, err := other.Bool("", "feature"); err == nil {
+		t.Fatal("bool string added to one configuration leaked into another")
+	}
+
+	c.SetStrictBool(true)
+	if _, err := c.Bool("", "debug"); err == nil {
+		t.Fatal(`strict c.Bool("", "debug") accepted "yes"`)
+	}
+	ans, err = c.Bool("", "strict")
+	verify(t, 1, "c.Bool", "", "strict", ans, true, err)
+}
+
+const gitConfFile = `
+[core]
+	bare = false
+[remote "Origin"]
+	url = git@example.com:repo.git
+[remote "with \"quotes\""]
+	url = /tmp/repo
+[remote.local]
+	url = ../repo
+`
+
+func TestSubsections(t *testing.T) {
+	c, err := ReadBytes([]byte(gitConfFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ans, err := c.String("Remote.Origin", "URL")
+	verify(t, 0, "c.String", "Remote.Origin", "URL", ans, "git@example.com:repo.git", err)
+	if c.HasSection("remote.origin") {
+		t.Fatal(`subsection name "Origin" matched case insensitively`)
+	}
+	ans, err = c.String(`remote.with "quotes"`, "url")
+	verify(t, 1, "c.String", `remote.with "quotes"`, "url", ans, "/tmp/repo", err)
+
+	subs := c.Subsections("remote")
+	verifyList(t, 2, "c.Subsections", "remote", "", subs, []string{"Origin", `with "quotes"`, "local"}, nil)
+
+	w, err := ReadBytes(c.WriteBytes(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subs = w.Subsections("remote")
+	verifyList(t, 3, "w.Subsections", "remote", "", subs, []string{"Origin", `with "quotes"`, "local"}, nil)
+}
+
+const treeConfFile = `
+[server]
+host = example.com
+timeout = 30
+
+[server.http]
+port = 80
+
+[server.http.tls]
+port = 443
+
+[server.grpc]
+port = 9000
+timeout = 10
+`
+
+func TestSub(t *testing.T) {
+	c, err := ReadBytes([]byte(treeConfFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []intTest{
+		{"server.http", "timeout", 30},
+		{"server.grpc", "timeout", 10},
+		{"server.http.tls", "port", 443},
+		{"server.http.tls", "timeout", 30},
+	}
+	for testnum, e := range tests {
+		ans, err := c.Int(e.section, e.option)
+		verify(t, testnum, "c.Int", e.section, e.option, ans, e.answer, err)
+	}
+
+	s := c.Sub("server")
+	verifyList(t, 0, "s.Sections", "", "", s.Sections(), []string{"default", "http", "http.tls", "grpc"}, nil)
+	verifyList(t, 1, "s.Subsections", "http", "", s.Subsections("http"), []string{"tls"}, nil)
+	ans, err := s.String("", "host")
+	verify(t, 2, "s.String", "", "host", ans, "example.com", err)
+	port, err := s.Int("http.tls", "port")
+	verify(t, 3, "s.Int", "http.tls", "port", port, 443, err)
+	options, err := s.Options("grpc")
+	verifyList(t, 4, "s.Options", "grpc", "", options, []string{"host", "timeout", "port"}, err)
+
+	s.AddOption("http", "path", "/")
+	s.AddOption("websocket", "port", "8080")
+	ans, err = c.String("server.http", "path")
+	verify(t, 5, "c.String", "server.http", "path", ans, "/", err)
+	port, err = c.Int("server.websocket", "port")
+	verify(t, 6, "c.Int", "server.websocket", "port", port, 8080, err)
+
+	w, err := ReadBytes(s.WriteBytes(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyList(t, 7, "w.Sections", "", "", w.Sections(), []string{"default", "http", "http.tls", "grpc", "websocket"}, nil)
+
+	// settings of the view do not apply to c
+	s.AddBoolString("enabled", true)
+	c.AddOption("server", "tls", "enabled")
+	if _, err := c.Bool("server", "tls"); err == nil {
+		t.Error("bool string added to the view accepted by c")
+	}
+	b, err := s.Bool("", "tls")
+	verify(t, 8, "s.Bool", "", "tls", b, true, err)
+}
+
+func TestCaseMode(t *testing.T) {
+	const file = "[JVM]\nJVM_Xmx = 2g\n[remote \"Origin\"]\nURL = /tmp\n"
+
+	tests := []struct {
+		mode     CaseMode
+		lookup   string
+		found    bool
+		expected string
+	}{
+		{CaseInsensitive, "jvm_xmx", true, "[jvm]\njvm_xmx=2g\n\n[remote \"Origin\"]\nurl=/tmp\n\n"},
+		{CasePreserving, "jvm_xmx", true, "[JVM]\nJVM_Xmx=2g\n\n[remote \"Origin\"]\nURL=/tmp\n\n"},
+		{CaseSensitive, "jvm_xmx", false, "[JVM]\nJVM_Xmx=2g\n\n[remote \"Origin\"]\nURL=/tmp\n\n"},
+	}
+
+	for testnum, e := range tests {
+		c := New()
+		c.SetCaseMode(e.mode)
+		if err := c.Read(strings.NewReader(file)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.String("JVM", e.lookup); (err == nil) != e.found {
+			t.Fatalf("%d. c.String(\"JVM\", %q) returned error %v", testnum, e.lookup, err)
+		}
+		ans, err := c.String("JVM", "JVM_Xmx")
+		verify(t, testnum, "c.String", "JVM", "JVM_Xmx", ans, "2g", err)
+		if !c.HasOption("JVM", "JVM_Xmx") || c.HasOption("JVM", e.lookup) != e.found {
+			t.Fatalf("%d. c.HasOption(\"JVM\", ...) ignored the case mode", testnum)
+		}
+		if out := string(c.WriteBytes("")); out != e.expected {
+			t.Fatalf("%d. c.WriteBytes wrote %q, expected %q", testnum, out, e.expected)
+		}
+	}
+}
+
+func TestMultiValue(t *testing.T) {
+	const file = "[Service]\nExecStartPre = /bin/a\nExecStartPre = /bin/b\n  --verbose\nExecStart = /bin/c\n"
+
+	c := New()
+	c.SetMultiValue(true)
+	if err := c.Read(strings.NewReader(file)); err != nil {
+		t.Fatal(err)
+	}
+
+	values := c.Values("service", "execstartpre")
+	verifyList(t, 0, "c.Values", "service", "execstartpre", values, []string{"/bin/a", "/bin/b\n--verbose"}, nil)
+	ans, err := c.String("service", "execstartpre")
+	verify(t, 1, "c.String", "service", "execstartpre", ans, "/bin/b\n--verbose", err)
+	values = c.Values("service", "execstart")
+	verifyList(t, 2, "c.Values", "service", "execstart", values, []string{"/bin/c"}, nil)
+
+	c.AppendOption("service", "execstart", "/bin/d")
+	expected := "[service]\nexecstartpre=/bin/a\nexecstartpre=/bin/b\n--verbose\nexecstart=/bin/c\nexecstart=/bin/d\n\n"
+	if out := string(c.WriteBytes("")); out != expected {
+		t.Fatalf("c.WriteBytes wrote %q, expected %q", out, expected)
+	}
+
+	c.AddOption("service", "execstart", "/bin/e")
+	values = c.Values("service", "execstart")
+	verifyList(t, 3, "c.Values", "service", "execstart", values, []string{"/bin/e"}, nil)
+
+	if r, _ := ReadBytes([]byte(file)); len(r.Values("service", "execstartpre")) != 1 {
+		t.Fatal("repeated option accumulated values without SetMultiValue")
+	}
+}
+
+func TestFlags(t *testing.T) {
+	const file = "[mysqld]\nskip-networking\nport = 3306\ninit = a\n  b\nquick ; comment\n"
+
+	if _, err := ReadBytes([]byte(file)); err == nil {
+		t.Fatal("option without value read without SetAllowNoValue")
+	}
+
+	c := New()
+	c.SetAllowNoValue(true)
+	if err := c.Read(strings.NewReader(file)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, option := range []string{"skip-networking", "quick"} {
+		if !c.HasOption("mysqld", option) || !c.IsFlag("mysqld", option) || c.HasValue("mysqld", option) {
+			t.Errorf("option %q not read as flag", option)
+		}
+	}
+	if c.IsFlag("mysqld", "port") || !c.HasValue("mysqld", "port") {
+		t.Error("option \"port\" read as flag")
+	}
+	ans, err := c.String("mysqld", "init")
+	verify(t, 0, "c.String", "mysqld", "init", ans, "a\nb", err)
+
+	c.AddOption("mysqld", "quick", "1")
+	if c.IsFlag("mysqld", "quick") {
+		t.Error("flag not cleared by AddOption")
+	}
+
+	expected := "[mysqld]\nskip-networking\nport=3306\ninit=a\n\tb\nquick=1\n\n"
+	if out := string(c.WriteBytes("")); out != expected {
+		t.Fatalf("c.WriteBytes wrote %q, expected %q", out, expected)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, err := ReadBytes([]byte("[a]\nn = x\nq = \"x, y\ne =\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.String("b", "n"); !errors.Is(err, ErrSectionNotFound) {
+		t.Errorf("c.String returned %v, expected ErrSectionNotFound", err)
+	}
+	if _, err := c.String("a", "m"); !errors.Is(err, ErrOptionNotFound) || errors.Is(err, ErrSectionNotFound) {
+		t.Errorf("c.String returned %v, expected ErrOptionNotFound", err)
+	}
+	_, err = c.Float64List("a", "n")
+	var numErr *strconv.NumError
+	if !errors.Is(err, ErrCouldNotParse) || !errors.As(err, &numErr) || numErr.Func != "ParseFloat" {
+		t.Errorf("c.Float64List returned %v, expected ErrCouldNotParse caused by strconv", err)
+	}
+	for _, option := range []string{"q", "e"} {
+		if _, err := c.IntList("a", option); !errors.Is(err, ErrCouldNotParse) {
+			t.Errorf("c.IntList returned %v for option '%s', expected ErrCouldNotParse", err, option)
+		}
+		if _, err := c.StringMap("a", option); option == "q" && !errors.Is(err, ErrCouldNotParse) {
+			t.Errorf("c.StringMap returned %v for option '%s', expected ErrCouldNotParse", err, option)
+		}
+	}
+	if _, err := ReadBytes([]byte("[a\n")); !errors.Is(err, ErrCouldNotParse) {
+		t.Errorf("ReadBytes returned %v, expected ErrCouldNotParse", err)
+	}
+}
+
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("read failed")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestReadErrors(t *testing.T) {
+	c := New()
+	if err := c.Read(&failingReader{[]byte("[a]\nb = 1\n")}); err == nil || err.Error() != "read failed" {
+		t.Errorf("c.Read returned %v, expected the reader error", err)
+	}
+
+	c = New()
+	c.SetMaxSize(16)
+	if err := c.Read(strings.NewReader("[a]\nb = 1\n")); err != nil {
+		t.Errorf("c.Read returned %v below the maximum size", err)
+	}
+	if err := c.Read(strings.NewReader("[a]\nb = 1\nc = 2\nd = 3\n")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("c.Read returned %v, expected ErrTooLarge", err)
+	}
+	if _, err := ReadJSON(LimitReader(strings.NewReader(`{"a": {"b": 1, "c": 2}}`), 16)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("ReadJSON returned %v, expected ErrTooLarge", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := New().ReadContext(ctx, strings.NewReader("[a]\nb = 1\n")); err != context.Canceled {
+		t.Errorf("c.ReadContext returned %v, expected context.Canceled", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// KeyProvider returns the 32-byte AES-256 key of encrypted values.
+type KeyProvider func() ([]byte, error)
+
+// StaticKey returns a KeyProvider always returning key.
+func StaticKey(key []byte) KeyProvider {
+	return func() ([]byte, error) {
+		return key, nil
+	}
+}
+
+const (
+	encPrefix = "ENC[AES256_GCM,"
+	encSuffix = "]"
+)
+
+var errMalformed = errors.New("conf: malformed encrypted value")
+
+// IsEncrypted reports whether value is an encrypted value of the form
+// ENC[AES256_GCM,data:...,iv:...,tag:...], as returned by EncryptValue.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
+}
+
+// EncryptValue encrypts the value of the given option in the section with
+// AES-256 in GCM mode, using a random IV. The names are authenticated as
+// additional data (section + "." + option), so that the result can only be
+// decrypted as the value of the same option.
+// The result has the form ENC[AES256_GCM,data:...,iv:...,tag:...], with
+// base64-encoded fields.
+func EncryptValue(key []byte, section string, option string, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nil, iv, []byte(value), []byte(section+"."+option))
+	data, tag := sealed[:len(value)], sealed[len(value):]
+
+	enc := base64.StdEncoding
+	return encPrefix + "data:" + enc.EncodeToString(data) + ",iv:" + enc.EncodeToString(iv) +
+		",tag:" + enc.EncodeToString(tag) + encSuffix, nil
+}
+
+// DecryptValue decrypts a value returned by EncryptValue for the same option
+// in the section.
+func DecryptValue(key []byte, section string, option string, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errMalformed
+	}
+	fields := make(map[string][]byte)
+	for _, field := range strings.Split(value[len(encPrefix):len(value)-len(encSuffix)], ",") {
+		i := strings.IndexByte(field, ':')
+		if i < 0 {
+			return "", errMalformed
+		}
+		b, err := base64.StdEncoding.DecodeString(field[i+1:])
+		if err != nil {
+			return "", errMalformed
+		}
+		fields[field[:i]] = b
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(fields["iv"]) != gcm.NonceSize() || len(fields["tag"]) != gcm.Overhead() {
+		return "", errMalformed
+	}
+
+	data, err := gcm.Open(nil, fields["iv"], append(fields["data"], fields["tag"]...), []byte(section+"."+option))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("conf: AES-256 key must be 32 bytes long")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Decrypter returns a ValueTransformer decrypting encrypted values (see
+// IsEncrypted) with the key of keys, for use with SetTransformer or Use.
+// Values are decrypted as those of the options of the sections they are
+// stored in, e.g. the parent section of an inherited option (see Encrypt).
+// Other values are returned unchanged.
+func Decrypter(keys KeyProvider) ValueTransformer {
+	return func(c *Config, section string, option string, value string) (string, error) {
+		if !IsEncrypted(value) {
+			return value, nil
+		}
+		key, err := keys()
+		if err != nil {
+			return "", err
+		}
+		section, option = c.sectionKey(section), c.optionKey(option)
+		if k, ok := c.lookupKey(section, option); ok {
+			section = k
+		}
+		return DecryptValue(key, section, option, value)
+	}
+}
+
+// Encrypt encrypts the values of the options for which selected returns true,
+// or of the secret options (see IsSecret) if selected is nil, with the key of
+// keys, e.g. before WriteFile. Values already encrypted are left as they are.
+// The values are bound to the section and option names as compared by the
+// configuration (see SetCaseMode).
+// It returns the number of options encrypted.
+func (c *Config) Encrypt(keys KeyProvider, selected func(section string, option string) bool) (n int, err error) {
+	if selected == nil {
+		selected = c.IsSecret
+	}
+	key, err := keys()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, section := range c.sectionKeys() {
+		for _, option := range c.options[section] {
+			if c.flags[section][option] || !selected(c.sectionName(section), c.optionName(section, option)) {
+				continue
+			}
+
+			values := c.values[section][option]
+			for i, value := range values {
+				if !IsEncrypted(value) {
+					if values[i], err = EncryptValue(key, section, option, value); err != nil {
+						return n, err
+					}
+				}
+			}
+			if value := c.data[section][option]; !IsEncrypted(value) {
+				if len(values) > 0 {
+					c.data[section][option] = values[len(values)-1]
+				} else if c.data[section][option], err = EncryptValue(key, section, option, value); err != nil {
+					return n, err
+				}
+				n++
+			}
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,78 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	c, err := ReadBytes([]byte("[db]\nuser = app\npassword = s3cr3t\nport = 5432\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := c.Encrypt(StaticKey(key), nil)
+	if err != nil || n != 1 {
+		t.Fatalf("c.Encrypt returned %d, %v, expected 1 secret option encrypted", n, err)
+	}
+	raw, _ := c.RawString("db", "password")
+	if !IsEncrypted(raw) || strings.Contains(raw, "s3cr3t") {
+		t.Fatalf("password not encrypted: %q", raw)
+	}
+	n, err = c.Encrypt(StaticKey(key), func(section, option string) bool { return option == "port" || option == "password" })
+	if err != nil || n != 1 {
+		t.Fatalf("c.Encrypt returned %d, %v, expected 1 option encrypted", n, err)
+	}
+
+	r, err := ReadBytes(c.WriteBytes(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Int("db", "port"); err == nil {
+		t.Error("encrypted value parsed without transformer")
+	}
+
+	r.SetTransformer(Decrypter(StaticKey(key)))
+	ans, err := r.String("db", "password")
+	verify(t, 0, "r.String", "db", "password", ans, "s3cr3t", err)
+	port, err := r.Int("db", "port")
+	verify(t, 1, "r.Int", "db", "port", port, 5432, err)
+	ans, err = r.String("db", "user")
+	verify(t, 2, "r.String", "db", "user", ans, "app", err)
+
+	// exports hold no decrypted value
+	data, err := r.TypedJSON()
+	if err != nil || strings.Contains(string(data), "5432") || strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("r.TypedJSON returned %s, %v", data, err)
+	}
+	b := r.Clone()
+	b.AddOption("db", "port", "5433")
+	if changes := DiffEffective(r, b); len(changes) != 1 || strings.Contains(changes[0].Old, "5432") {
+		t.Errorf("DiffEffective returned %v", changes)
+	}
+
+	// inherited values are decrypted as those of their section, also in views
+	r.AddSection("db.replica")
+	ans, err = r.String("db.replica", "password")
+	verify(t, 3, "r.String", "db.replica", "password", ans, "s3cr3t", err)
+	ans, err = r.Sub("db").String("replica", "password")
+	verify(t, 4, "r.Sub.String", "replica", "password", ans, "s3cr3t", err)
+
+	// a value copied to another option does not decrypt
+	raw, _ = r.RawString("db", "password")
+	r.AddOption("db", "user", raw)
+	if _, err := r.String("db", "user"); err == nil {
+		t.Error("value decrypted as the value of another option")
+	}
+
+	r.SetTransformer(Decrypter(StaticKey(bytes.Repeat([]byte{8}, 32))))
+	if _, err := r.String("db", "password"); err == nil {
+		t.Error("value decrypted with the wrong key")
+	}
+}
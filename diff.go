@@ -0,0 +1,305 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ChangeType is the kind of a Change between two configurations.
+type ChangeType int
+
+const (
+	SectionAdded ChangeType = iota
+	SectionRemoved
+	OptionAdded
+	OptionRemoved
+	OptionModified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case SectionAdded:
+		return "section added"
+	case SectionRemoved:
+		return "section removed"
+	case OptionAdded:
+		return "option added"
+	case OptionRemoved:
+		return "option removed"
+	case OptionModified:
+		return "option modified"
+	}
+
+	return "invalid change"
+}
+
+// Change describes a difference between two configurations, as returned by
+// Diff. Option is empty for changes of whole sections.
+// For multi-valued options (see AppendOption), Old and New hold the last
+// value, as returned by String, and OldValues and NewValues all values.
+type Change struct {
+	Type      ChangeType
+	Section   string
+	Option    string
+	Old       string   // Value in the old configuration (empty if added).
+	New       string   // Value in the new configuration (empty if removed).
+	Secret    bool     // The option is secret: String and WriteDiff redact its values.
+	OldValues []string // All values in the old configuration if there are several, else nil.
+	NewValues []string // All values in the new configuration if there are several, else nil.
+}
+
+func (ch Change) String() string {
+	if ch.Secret {
+		ch = ch.redacted()
+	}
+	old, new := fmt.Sprintf("%q", ch.Old), fmt.Sprintf("%q", ch.New)
+	if ch.OldValues != nil {
+		old = fmt.Sprintf("%q", ch.OldValues)
+	}
+	if ch.NewValues != nil {
+		new = fmt.Sprintf("%q", ch.NewValues)
+	}
+
+	switch ch.Type {
+	case SectionAdded, SectionRemoved:
+		return fmt.Sprintf("%s: [%s]", ch.Type, ch.Section)
+	case OptionAdded:
+		return fmt.Sprintf("%s: [%s] %s = %s", ch.Type, ch.Section, ch.Option, new)
+	case OptionRemoved:
+		return fmt.Sprintf("%s: [%s] %s = %s", ch.Type, ch.Section, ch.Option, old)
+	}
+	return fmt.Sprintf("%s: [%s] %s = %s -> %s", ch.Type, ch.Section, ch.Option, old, new)
+}
+
+// oldValues returns all values of the option in the old configuration.
+func (ch Change) oldValues() []string {
+	if ch.OldValues != nil {
+		return ch.OldValues
+	}
+	return []string{ch.Old}
+}
+
+// newValues returns all values of the option in the new configuration.
+func (ch Change) newValues() []string {
+	if ch.NewValues != nil {
+		return ch.NewValues
+	}
+	return []string{ch.New}
+}
+
+// redacted returns the change with its values redacted.
+func (ch Change) redacted() Change {
+	ch.Old, ch.New = redact(ch.Old), redact(ch.New)
+	ch.OldValues, ch.NewValues = redactAll(ch.OldValues), redactAll(ch.NewValues)
+	return ch
+}
+
+func redactAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	redacted := make([]string, len(values))
+	for i, value := range values {
+		redacted[i] = redact(value)
+	}
+	return redacted
+}
+
+// newChange returns the change of an option from the values from to the
+// values to (nil if the option was added or removed).
+func newChange(t ChangeType, section string, option string, from []string, to []string, secret bool) Change {
+	ch := Change{Type: t, Section: section, Option: option, Secret: secret}
+	if len(from) > 0 {
+		ch.Old = from[len(from)-1]
+	}
+	if len(to) > 0 {
+		ch.New = to[len(to)-1]
+	}
+	if len(from) > 1 {
+		ch.OldValues = from
+	}
+	if len(to) > 1 {
+		ch.NewValues = to
+	}
+	return ch
+}
+
+// Diff returns the changes from configuration a to configuration b, comparing
+// the raw values of the options stored in each section, all values of
+// multi-valued options included. Added and removed
+// sections are followed by the changes of all their options.
+// Sections are listed in the order of a, then the sections added in b.
+// Changes of options that are secret in a or b are marked Secret.
+func Diff(a, b *Config) []Change {
+	options := func(c *Config, section string) []string {
+		key := c.sectionKey(section)
+		names := make([]string, len(c.options[key]))
+		for i, option := range c.options[key] {
+			names[i] = c.optionName(key, option)
+		}
+		return names
+	}
+	value := func(c *Config, section string, option string) []string {
+		return c.Values(section, option)
+	}
+
+	return diff(a, b, options, value)
+}
+
+// DiffEffective has the same behaviour as Diff but compares the effective
+// options of each section, as returned by Options, and their values as
+// returned by String: options of the default section and inherited options
+// are included (the latter taking precedence). Values are unfolded (see
+// Unfold) even if the configuration does not use Unfold. Values which cannot
+// be transformed are compared raw: their errors are ignored. Values of secret
+// options and encrypted values are compared raw as well, so that changes
+// hold no decrypted value or resolved secret.
+func DiffEffective(a, b *Config) []Change {
+	options := func(c *Config, section string) []string {
+		names, _ := c.Options(section)
+		return names
+	}
+	value := func(c *Config, section string, option string) []string {
+		if !c.HasOption(section, option) {
+			return nil
+		}
+		if _, err := c.RawString(section, option); err != nil {
+			section = DefaultSection
+		}
+		values := c.Values(section, option)
+		secret := c.IsSecret(section, option)
+		for i, v := range values {
+			if secret || IsEncrypted(v) {
+				continue
+			}
+			if v, err := c.transformValue(section, option, v); err == nil {
+				values[i] = v
+			}
+			if !c.uses(Unfold) {
+				if v, err := Unfold(c, section, option, values[i]); err == nil {
+					values[i] = v
+				}
+			}
+		}
+		return values
+	}
+
+	return diff(a, b, options, value)
+}
+
+func diff(a, b *Config, options func(c *Config, section string) []string, value func(c *Config, section string, option string) []string) (changes []Change) {
+	// has reports whether the option is among the options of the section.
+	has := func(c *Config, section string, option string, names []string) bool {
+		option = c.optionKey(option)
+		for _, name := range names {
+			if c.optionKey(name) == option {
+				return true
+			}
+		}
+		return false
+	}
+	change := func(t ChangeType, section string, option string, from []string, to []string) Change {
+		secret := option != "" && (a.IsSecret(section, option) || b.IsSecret(section, option))
+		return newChange(t, section, option, from, to, secret)
+	}
+
+	for _, section := range a.Sections() {
+		if !b.HasSection(section) {
+			changes = append(changes, change(SectionRemoved, section, "", nil, nil))
+			for _, option := range options(a, section) {
+				changes = append(changes, change(OptionRemoved, section, option, value(a, section, option), nil))
+			}
+			continue
+		}
+
+		aopts, bopts := options(a, section), options(b, section)
+		for _, option := range aopts {
+			old := value(a, section, option)
+			switch {
+			case !has(b, section, option, bopts):
+				changes = append(changes, change(OptionRemoved, section, option, old, nil))
+			case !reflect.DeepEqual(old, value(b, section, option)):
+				changes = append(changes, change(OptionModified, section, option, old, value(b, section, option)))
+			}
+		}
+		for _, option := range bopts {
+			if !has(a, section, option, aopts) {
+				changes = append(changes, change(OptionAdded, section, option, nil, value(b, section, option)))
+			}
+		}
+	}
+
+	for _, section := range b.Sections() {
+		if a.HasSection(section) {
+			continue
+		}
+		changes = append(changes, change(SectionAdded, section, "", nil, nil))
+		for _, option := range options(b, section) {
+			changes = append(changes, change(OptionAdded, section, option, nil, value(b, section, option)))
+		}
+	}
+
+	return changes
+}
+
+// WriteDiff writes the changes to the io.Writer in a format resembling a
+// unified diff: each section with changes is introduced by its header, and
+// removed and added lines are prefixed with "-" and "+". The header of an
+// added or removed section is prefixed as well. Multi-valued options have a
+// line per value. Values of secret options are redacted.
+func WriteDiff(writer io.Writer, changes []Change) (err error) {
+	buf := bytes.NewBuffer(nil)
+
+	section := ""
+	for i, ch := range changes {
+		if i == 0 || ch.Section != section {
+			section = ch.Section
+			prefix := " "
+			switch ch.Type {
+			case SectionAdded:
+				prefix = "+"
+			case SectionRemoved:
+				prefix = "-"
+			}
+			if _, err = buf.WriteString(prefix + "[" + section + "]\n"); err != nil {
+				return err
+			}
+		}
+
+		if ch.Secret {
+			ch = ch.redacted()
+		}
+		if ch.Type == OptionRemoved || ch.Type == OptionModified {
+			for _, value := range ch.oldValues() {
+				if err = writeDiffLine(buf, "-", ch.Option, value); err != nil {
+					return err
+				}
+			}
+		}
+		if ch.Type == OptionAdded || ch.Type == OptionModified {
+			for _, value := range ch.newValues() {
+				if err = writeDiffLine(buf, "+", ch.Option, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err = buf.WriteTo(writer)
+
+	return err
+}
+
+// writeDiffLine writes an option line with the prefix. Continuation lines
+// of multi-line values are indented.
+func writeDiffLine(buf *bytes.Buffer, prefix string, option string, value string) error {
+	value = strings.Replace(value, "\n", "\n"+prefix+"\t", -1)
+	_, err := buf.WriteString(prefix + option + "=" + value + "\n")
+	return err
+}
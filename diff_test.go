@@ -0,0 +1,111 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := ReadBytes([]byte("host = a\n[db]\nuser = x\nport = 1\n[old]\nk = v\n[server]\nname = s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadBytes([]byte("host = b\n[db]\nport = 2\npass = p\n[new]\nk = w\n[server]\nname = s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(a, b)
+	expected := []Change{
+		{OptionModified, "default", "host", "a", "b", false, nil, nil},
+		{OptionRemoved, "db", "user", "x", "", false, nil, nil},
+		{OptionModified, "db", "port", "1", "2", false, nil, nil},
+		{OptionAdded, "db", "pass", "", "p", false, nil, nil},
+		{SectionRemoved, "old", "", "", "", false, nil, nil},
+		{OptionRemoved, "old", "k", "v", "", false, nil, nil},
+		{SectionAdded, "new", "", "", "", false, nil, nil},
+		{OptionAdded, "new", "k", "", "w", false, nil, nil},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Diff returned %v, expected %v", changes, expected)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := WriteDiff(buf, changes); err != nil {
+		t.Fatal(err)
+	}
+	text := " [default]\n-host=a\n+host=b\n [db]\n-user=x\n-port=1\n+port=2\n+pass=p\n-[old]\n-k=v\n+[new]\n+k=w\n"
+	if buf.String() != text {
+		t.Errorf("WriteDiff wrote %q, expected %q", buf.String(), text)
+	}
+
+	// the changed default value is also effective in every section
+	changes = DiffEffective(a, b)
+	if len(changes) != 12 || !reflect.DeepEqual(changes[8], Change{OptionModified, "server", "host", "a", "b", false, nil, nil}) {
+		t.Errorf("DiffEffective returned %v", changes)
+	}
+
+	// unfolded values are compared
+	a.AddOption("server", "url", "http://%(host)s/")
+	b.AddOption("server", "url", "http://%(host)s/")
+	changes = DiffEffective(a, b)
+	if ch := changes[len(changes)-4]; !reflect.DeepEqual(ch, Change{OptionModified, "server", "url", "http://a/", "http://b/", false, nil, nil}) {
+		t.Errorf("DiffEffective returned %v for unfolded values", ch)
+	}
+
+	// configured transformers are applied once, Unfold included
+	a.Use(Unfold)
+	a.Use(func(c *Config, section, option, value string) (string, error) {
+		return value + "%(host)s", nil
+	})
+	changes = DiffEffective(a, b)
+	if ch := changes[len(changes)-4]; ch.Old != "http://a/%(host)s" {
+		t.Errorf("DiffEffective returned %v for transformed values", ch)
+	}
+}
+
+func TestDiffMultiValue(t *testing.T) {
+	a, b := New(), New()
+	a.SetMultiValue(true)
+	b.SetMultiValue(true)
+	if err := a.Read(strings.NewReader("[s]\nv = 1\nv = 3\nw = 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Read(strings.NewReader("[s]\nv = 2\nv = 3\nw = 1\nw = 2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(a, b)
+	expected := []Change{
+		{OptionModified, "s", "v", "3", "3", false, []string{"1", "3"}, []string{"2", "3"}},
+		{OptionModified, "s", "w", "1", "2", false, nil, []string{"1", "2"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Diff returned %v, expected %v", changes, expected)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := WriteDiff(buf, changes); err != nil {
+		t.Fatal(err)
+	}
+	text := " [s]\n-v=1\n-v=3\n+v=2\n+v=3\n-w=1\n+w=1\n+w=2\n"
+	if buf.String() != text {
+		t.Errorf("WriteDiff wrote %q, expected %q", buf.String(), text)
+	}
+
+	if conflicts := a.Apply(changes); len(conflicts) != 0 {
+		t.Fatalf("a.Apply returned %v", conflicts)
+	}
+	values := a.Values("s", "v")
+	verifyList(t, 0, "a.Values", "s", "v", values, []string{"2", "3"}, nil)
+	values = a.Values("s", "w")
+	verifyList(t, 1, "a.Values", "s", "w", values, []string{"1", "2"}, nil)
+	if changes = Diff(a, b); len(changes) != 0 {
+		t.Errorf("Diff returned %v after Apply", changes)
+	}
+}
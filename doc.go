@@ -14,6 +14,7 @@ Given the configuration file:
 
 	list-str = hello, world
 	list-int = 1, 2, 3
+	labels = env=prod, team=core
 
 	[service-1]
 	host = s1.example.com
@@ -28,15 +29,30 @@ To read this configuration file, do:
 
 	c.StringList("default", "list-str")		// return ["hello", "world"]
 	c.IntList("default", "list-int")		// return [1, 2, 3]
+	c.StringMap("default", "labels")		// return {"env": "prod", "team": "core"}
 
 	c.String("service-1", "host")           // returns s1.example.com
 	c.Bool("service-1","allow-writing")     // returns false
 	c.Int("service-1", "port")              // returns 0 and a GetError
 
-Note that all section and option names are case insensitive. All values
-are case sensitive.
-
-Goconfig's string substitution syntax has not been removed. However, it may be
-taken out or modified in the future.
+Configurations from untrusted sources can be read with ReadFileLimit or
+LoadLimit, or by wrapping the reader passed to Read, ReadJSON, ReadYAML, etc.
+with LimitReader, which fail with ErrTooLarge beyond a maximum size.
+
+Note that all section and option names are case insensitive (see
+Config.SetCaseMode to preserve their case or compare them exactly). All values
+are case sensitive. Git-style subsections, as in [remote "origin"], are
+accessed as "remote.origin" and keep the case of their subsection name.
+
+Options whose name looks like a password or token are secret: their values
+are redacted when the configuration is printed with fmt (see Config.Redacted
+and Config.SetSecret). Secret references in their values, such as
+"secret://file/run/secrets/db", are resolved by the ResolveSecrets
+transformer.
+
+Goconfig's string substitution syntax, as in %(host)s, is available through
+the Unfold transformer: call c.Use(conf.Unfold) to have String unfold
+values. Other transformers, e.g. ExpandEnv, Decrypter(keys) or
+ResolveSecrets(resolvers), are added the same way.
 */
 package conf
@@ -0,0 +1,147 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ReadEnv reads a dotenv (.env) file and returns a new configuration
+// representation with its variables as options of the default section.
+// Lines may start with "export". Values may be unquoted (a " #" starts a
+// comment), single-quoted (taken literally) or double-quoted (with \n, \t,
+// \", \\ and \$ escapes); quoted values may span several lines.
+// Variable references like ${VAR} are not expanded.
+// Variable names keep their case (see CasePreserving), so that WriteEnv
+// writes them as read.
+func ReadEnv(reader io.Reader) (c *Config, err error) {
+	c = New()
+	c.SetCaseMode(CasePreserving)
+	if err = c.readEnv(reader, DefaultSection); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// readEnv adds the variables of the dotenv file to section.
+func (c *Config) readEnv(reader io.Reader, section string) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		pos := Position{Line: i + 1}
+		l := strings.TrimSpace(lines[i])
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(l, "export ") || strings.HasPrefix(l, "export\t") {
+			l = strings.TrimSpace(l[len("export"):])
+		}
+
+		eq := strings.IndexByte(l, '=')
+		if eq <= 0 {
+			return ReadError{CouldNotParse, l}
+		}
+		name := strings.TrimSpace(l[:eq])
+		if strings.ContainsAny(name, " \t'\"") {
+			return ReadError{CouldNotParse, l}
+		}
+		value := strings.TrimLeft(l[eq+1:], " \t")
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			// quoted values may span lines up to the closing quote
+			q := value[0]
+			value = value[1:]
+			for {
+				if end := envQuoteEnd(value, q); end >= 0 {
+					rest := strings.TrimSpace(value[end+1:])
+					if rest != "" && rest[0] != '#' {
+						return ReadError{CouldNotParse, l}
+					}
+					value = value[:end]
+					break
+				}
+				if i++; i == len(lines) {
+					return ReadError{CouldNotParse, l}
+				}
+				value += "\n" + lines[i]
+			}
+			if q == '"' {
+				value = unescapeEnv(value)
+			}
+		} else {
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = value[:j]
+			}
+			value = strings.TrimSpace(value)
+		}
+
+		c.AddOption(section, name, value)
+		c.setPosition(section, name, pos)
+	}
+
+	return nil
+}
+
+// envQuoteEnd returns the index of the closing quote q in s, or -1.
+func envQuoteEnd(s string, q byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && q == '"':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	envUnescaper = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\r`, "\r", `\"`, `"`, `\\`, `\`, `\$`, `$`)
+	envEscaper   = strings.NewReplacer("\n", `\n`, "\t", `\t`, "\r", `\r`, `"`, `\"`, `\`, `\\`, `$`, `\$`)
+)
+
+func unescapeEnv(s string) string {
+	return envUnescaper.Replace(s)
+}
+
+// WriteEnv writes the options of the given section (the default section if
+// empty) to the io.Writer as a dotenv file. Values that are empty or contain
+// white space, quotes, '#', '$' or '\' are double-quoted.
+// The header is written as a comment in the first line.
+func (c *Config) WriteEnv(writer io.Writer, section string, header string) (err error) {
+	section = c.sectionKey(section)
+
+	buf := bytes.NewBuffer(nil)
+
+	if header != "" {
+		if _, err = buf.WriteString("# " + header + "\n"); err != nil {
+			return err
+		}
+	}
+
+	for _, option := range c.options[section] {
+		if err = writeComment(buf, c.comments[section][option]); err != nil {
+			return err
+		}
+		value := c.data[section][option]
+		if value == "" || strings.ContainsAny(value, " \t\r\n'\"#$\\") {
+			value = `"` + envEscaper.Replace(value) + `"`
+		}
+		if _, err = buf.WriteString(c.optionName(section, option) + "=" + value + "\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err = buf.WriteTo(writer)
+
+	return err
+}
@@ -0,0 +1,59 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const envFile = `# Comment
+HOST=example.com
+export PORT=443 # comment
+SINGLE='a "b" \n'
+DOUBLE="a\t\"b\"
+c"
+EMPTY=
+`
+
+func TestEnv(t *testing.T) {
+	c, err := ReadEnv(strings.NewReader(envFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []stringTest{
+		{"", "host", "example.com"},
+		{"", "port", "443"},
+		{"", "single", `a "b" \n`},
+		{"", "double", "a\t\"b\"\nc"},
+		{"", "empty", ""},
+	}
+	check := func(c *Config) {
+		for testnum, e := range tests {
+			ans, err := c.String(e.section, e.option)
+			verify(t, testnum, "c.String", e.section, e.option, ans, e.answer, err)
+		}
+	}
+	check(c)
+
+	buf := bytes.NewBuffer(nil)
+	if err := c.WriteEnv(buf, "", "header"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\nHOST=example.com\nPORT=443\n") {
+		t.Errorf("c.WriteEnv did not keep the case of names: %q", buf.String())
+	}
+	if c, err = ReadEnv(buf); err != nil {
+		t.Fatal(err)
+	}
+	check(c)
+
+	for _, bad := range []string{"FOO", `FOO="bar`, "FOO='bar' baz"} {
+		if _, err := ReadEnv(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadEnv(%q) returned no error", bad)
+		}
+	}
+}
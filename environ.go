@@ -0,0 +1,136 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"os"
+	"strings"
+)
+
+// EnvOptions controls how ApplyEnv maps environment variables to options.
+type EnvOptions struct {
+	Separator     string   // Separates section and option names ("__" if empty).
+	CaseSensitive bool     // Match names exactly instead of in upper case.
+	KeepDashes    bool     // Do not translate '-' and '.' in names to '_'.
+	AddMissing    bool     // Add options for variables matching no option.
+	Environ       []string // Variables as "NAME=value" (os.Environ() if nil).
+}
+
+// Override describes an option set by ApplyEnv.
+type Override struct {
+	Variable string // Name of the environment variable.
+	Change   Change // OptionModified or OptionAdded change of the option.
+}
+
+// envName returns the environment variable name (without prefix) of the
+// option in the section.
+func (opts *EnvOptions) envName(section string, option string) string {
+	name := option
+	if section != DefaultSection {
+		name = section + opts.separator() + option
+	}
+	if !opts.KeepDashes {
+		name = strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	}
+	return opts.fold(name)
+}
+
+func (opts *EnvOptions) separator() string {
+	if opts.Separator == "" {
+		return "__"
+	}
+	return opts.Separator
+}
+
+// fold returns name as compared by ApplyEnv.
+func (opts *EnvOptions) fold(name string) string {
+	if opts.CaseSensitive {
+		return name
+	}
+	return strings.ToUpper(name)
+}
+
+// ApplyEnv overrides options with the environment variables named prefix +
+// section + separator + option, e.g. APP_SERVICE_1__PORT for option "port"
+// of section "service-1" with prefix "APP_" (see EnvOptions), or prefix +
+// option for the options of the default section.
+// With AddMissing, variables starting with prefix that match no option add
+// one, in the section named before the separator (or the default section);
+// new names are lower-cased unless CaseSensitive is set.
+// It returns the options set, in configuration order followed by the
+// options added in environment order.
+func (c *Config) ApplyEnv(prefix string, opts EnvOptions) (overrides []Override) {
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+
+	type variable struct{ name, value string }
+	vars := make(map[string]variable) // Maps folded names without prefix to variables.
+	var order []string
+	for _, kv := range environ {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 || !strings.HasPrefix(opts.fold(kv[:i]), opts.fold(prefix)) {
+			continue
+		}
+		name := opts.fold(kv[len(prefix):i])
+		if _, ok := vars[name]; !ok {
+			order = append(order, name)
+		}
+		vars[name] = variable{kv[:i], kv[i+1:]}
+	}
+
+	used := make(map[string]bool)
+	sections := make(map[string]string) // Maps folded names to section names.
+	for _, key := range c.sectionKeys() {
+		section := c.sectionName(key)
+		sections[opts.envName(DefaultSection, section)] = section
+		for _, option := range c.options[key] {
+			name := opts.envName(section, c.optionName(key, option))
+			v, ok := vars[name]
+			if !ok {
+				continue
+			}
+			used[name] = true
+			change := newChange(OptionModified, section, c.optionName(key, option), c.Values(section, option), []string{v.value}, c.IsSecret(section, option))
+			c.AddOption(section, change.Option, v.value)
+			overrides = append(overrides, Override{v.name, change})
+		}
+	}
+
+	if !opts.AddMissing {
+		return overrides
+	}
+	for _, name := range order {
+		if used[name] || name == "" {
+			continue
+		}
+		v := vars[name]
+		section, option := DefaultSection, v.name[len(prefix):]
+		if i := strings.Index(option, opts.separator()); i > 0 {
+			section, option = option[:i], option[i+len(opts.separator()):]
+			if s, ok := sections[opts.fold(section)]; ok {
+				section = s
+			} else if !opts.CaseSensitive {
+				section = strings.ToLower(section)
+			}
+		}
+		if !opts.CaseSensitive {
+			option = strings.ToLower(option)
+		}
+		if option == "" {
+			continue
+		}
+		c.AddOption(section, option, v.value)
+		overrides = append(overrides, Override{v.name, newChange(OptionAdded, section, option, nil, []string{v.value}, c.IsSecret(section, option))})
+	}
+
+	return overrides
+}
+
+// ExpandEnv is a ValueTransformer replacing ${VAR} and $VAR in values by the
+// value of the environment variable (see os.ExpandEnv). Use it with Use.
+func ExpandEnv(c *Config, section string, option string, value string) (string, error) {
+	return os.ExpandEnv(value), nil
+}
@@ -0,0 +1,51 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	c, err := ReadBytes([]byte("host = example.com\n[service-1]\nport = 80\n[server.http]\nlisten = :80\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	environ := []string{
+		"APP_SERVICE_1__PORT=8080",
+		"APP_HOST=localhost",
+		"app_server_http__listen=:8000",
+		"APP_SERVICE_1__NAME=web",
+		"APP_NEW__KEY=v",
+		"OTHER_HOST=x",
+	}
+	overrides := c.ApplyEnv("APP_", EnvOptions{Environ: environ})
+	expected := []Override{
+		{"APP_HOST", Change{OptionModified, "default", "host", "example.com", "localhost", false, nil, nil}},
+		{"APP_SERVICE_1__PORT", Change{OptionModified, "service-1", "port", "80", "8080", false, nil, nil}},
+		{"app_server_http__listen", Change{OptionModified, "server.http", "listen", ":80", ":8000", false, nil, nil}},
+	}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Fatalf("c.ApplyEnv returned %v, expected %v", overrides, expected)
+	}
+	if c.HasSection("new") {
+		t.Error("c.ApplyEnv added a section without AddMissing")
+	}
+
+	overrides = c.ApplyEnv("APP_", EnvOptions{Environ: environ, AddMissing: true})
+	if len(overrides) != 5 {
+		t.Fatalf("c.ApplyEnv returned %v, expected 5 overrides", overrides)
+	}
+	ans, err := c.String("service-1", "name")
+	verify(t, 0, "c.String", "service-1", "name", ans, "web", err)
+	ans, err = c.String("new", "key")
+	verify(t, 1, "c.String", "new", "key", ans, "v", err)
+
+	overrides = c.ApplyEnv("APP_", EnvOptions{Environ: environ, CaseSensitive: true})
+	if len(overrides) != 0 {
+		t.Errorf("c.ApplyEnv matched %v case sensitively", overrides)
+	}
+}
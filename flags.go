@@ -0,0 +1,100 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"flag"
+	"strings"
+)
+
+// flagValue is a flag.Value holding the value of an option given on the
+// command line.
+type flagValue struct {
+	section string
+	option  string
+	value   string
+	isBool  bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+// IsBoolFlag lets bool options be given as -name instead of -name=true.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// FlagName returns the name of the command-line flag for the given option in
+// the section: "section.option", or "option" for the default section.
+func FlagName(section string, option string) string {
+	if section == "" || strings.ToLower(section) == DefaultSection {
+		return option
+	}
+	return section + "." + option
+}
+
+// RegisterFlags defines a flag in fs for every option of the configuration,
+// named as by FlagName, with the current value as default (except for secret
+// options) and the comment of the option as usage. Options with the value
+// "true" or "false" are bool flags. Flags already defined in fs are left
+// untouched.
+// Use ApplyFlags after parsing the command line to override the options.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	for _, key := range c.sectionKeys() {
+		section := c.sectionName(key)
+		for _, option := range c.options[key] {
+			value := c.data[key][option]
+			if c.IsSecret(section, option) {
+				value = "" // not shown as default
+			}
+			isBool := strings.EqualFold(value, "true") || strings.EqualFold(value, "false")
+			usage := strings.Replace(c.comments[key][option], "\n", " ", -1)
+			registerFlag(fs, section, c.optionName(key, option), value, usage, isBool)
+		}
+	}
+}
+
+// RegisterFlags defines a flag in fs for every option declared in the schema,
+// named as by FlagName, with the declared default and help. Options of type
+// TypeBool are bool flags. Flags already defined in fs are left untouched.
+// Use ApplyFlags after parsing the command line to override the options.
+func (s *Schema) RegisterFlags(fs *flag.FlagSet) {
+	for _, ss := range s.Sections {
+		for _, opt := range ss.Options {
+			registerFlag(fs, ss.Name, opt.Name, opt.Default, opt.Help, opt.Type == TypeBool)
+		}
+	}
+}
+
+func registerFlag(fs *flag.FlagSet, section string, option string, value string, usage string, isBool bool) {
+	name := FlagName(section, option)
+	if fs.Lookup(name) != nil {
+		return
+	}
+	fs.Var(&flagValue{section, option, value, isBool}, name, usage)
+}
+
+// ApplyFlags overrides the options of the configuration with the flags of fs
+// which were set on the command line, among those defined by RegisterFlags.
+// Options given as flags are added if missing.
+// It returns the number of options set.
+func (c *Config) ApplyFlags(fs *flag.FlagSet) (n int) {
+	fs.Visit(func(f *flag.Flag) {
+		if v, ok := f.Value.(*flagValue); ok {
+			c.AddOption(v.section, v.option, v.value)
+			n++
+		}
+	})
+
+	return n
+}
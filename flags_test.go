@@ -0,0 +1,52 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	c, err := ReadBytes([]byte("host = example.com\n[service-1]\nport = 80\ndebug = false\nname = web\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	c.RegisterFlags(fs)
+
+	s := &Schema{Sections: []SectionSchema{{Name: "log", Options: []OptionSchema{
+		{Name: "verbose", Type: TypeBool, Help: "log more"},
+	}}}}
+	s.RegisterFlags(fs)
+
+	if f := fs.Lookup("service-1.port"); f == nil || f.DefValue != "80" {
+		t.Fatalf("flag service-1.port not registered: %v", f)
+	}
+	if f := fs.Lookup("log.verbose"); f == nil || f.Usage != "log more" {
+		t.Fatalf("flag log.verbose not registered: %v", f)
+	}
+
+	args := []string{"-service-1.port=8080", "-service-1.debug", "-log.verbose", "-host", "localhost"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.ApplyFlags(fs); n != 4 {
+		t.Errorf("c.ApplyFlags returned %d, expected 4", n)
+	}
+
+	for i, e := range []struct{ section, option, value string }{
+		{"default", "host", "localhost"},
+		{"service-1", "port", "8080"},
+		{"service-1", "debug", "true"},
+		{"service-1", "name", "web"},
+		{"log", "verbose", "true"},
+	} {
+		ans, err := c.String(e.section, e.option)
+		verify(t, i, "c.String", e.section, e.option, ans, e.value, err)
+	}
+}
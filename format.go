@@ -0,0 +1,187 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FormatReader reads a configuration file in some format.
+type FormatReader func(reader io.Reader) (*Config, error)
+
+// FormatWriter writes a configuration file in some format. The header is
+// saved as a comment if the format allows it.
+type FormatWriter func(c *Config, writer io.Writer, header string) error
+
+type format struct {
+	read  FormatReader
+	write FormatWriter
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]format) // Maps lower-case names to formats.
+)
+
+func init() {
+	readINI := func(reader io.Reader) (*Config, error) {
+		c := New()
+		if err := c.Read(reader); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	writeINI := func(c *Config, writer io.Writer, header string) error {
+		return c.Write(writer, header)
+	}
+	writeJSON := func(c *Config, writer io.Writer, header string) error {
+		data, err := c.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(append(data, '\n'))
+		return err
+	}
+	writeEnv := func(c *Config, writer io.Writer, header string) error {
+		return c.WriteEnv(writer, DefaultSection, header)
+	}
+	writeProperties := func(c *Config, writer io.Writer, header string) error {
+		return c.WriteProperties(writer, header)
+	}
+
+	RegisterFormat("ini", readINI, writeINI)
+	RegisterFormat("conf", readINI, writeINI)
+	RegisterFormat("cfg", readINI, writeINI)
+	RegisterFormat("json", ReadJSON, writeJSON)
+	RegisterFormat("toml", ReadTOML, nil)
+	RegisterFormat("yaml", ReadYAML, nil)
+	RegisterFormat("yml", ReadYAML, nil)
+	RegisterFormat("env", ReadEnv, writeEnv)
+	RegisterFormat("properties", ReadProperties, writeProperties)
+}
+
+// RegisterFormat registers a file format for Load and Save under the given
+// name, which is also the file extension (without dot) it is used for.
+// Either function may be nil if the format cannot be read or written.
+// Registering an existing name replaces the format.
+func RegisterFormat(name string, reader FormatReader, writer FormatWriter) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	formats[strings.ToLower(name)] = format{reader, writer}
+}
+
+// lookupFormat returns the format registered under name.
+func lookupFormat(name string) (f format, ok bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	f, ok = formats[strings.ToLower(name)]
+	return f, ok
+}
+
+// Load reads a file and returns a new configuration representation, using
+// the format registered for the file extension (see RegisterFormat).
+// For unknown extensions the format is guessed from the content: JSON,
+// dotenv (for .env* files), YAML or else INI.
+func Load(fname string) (c *Config, err error) {
+	return LoadLimit(fname, 0)
+}
+
+// LoadLimit has the same behaviour as Load but fails with ErrTooLarge if the
+// file has more than n bytes (0 for no limit).
+func LoadLimit(fname string, n int64) (c *Config, err error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if n > 0 {
+		reader = LimitReader(file, n)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := lookupFormat(strings.TrimPrefix(filepath.Ext(fname), "."))
+	if !ok || f.read == nil {
+		name := sniffFormat(fname, data)
+		if f, ok = lookupFormat(name); !ok || f.read == nil {
+			return nil, fmt.Errorf("conf: no reader registered for format %q", name)
+		}
+	}
+
+	if c, err = f.read(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	c.setFilename(fname)
+
+	return c, nil
+}
+
+// Save saves the configuration representation to a file, using the format
+// registered for the file extension (see RegisterFormat).
+// The desired file permissions must be passed as in os.Open.
+// The header is saved as a comment if the format allows it.
+func (c *Config) Save(fname string, perm uint32, header string) (err error) {
+	name := strings.TrimPrefix(filepath.Ext(fname), ".")
+	f, ok := lookupFormat(name)
+	if !ok || f.write == nil {
+		return fmt.Errorf("conf: no writer registered for format %q", name)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = f.write(c, buf, header); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fname, buf.Bytes(), os.FileMode(perm))
+}
+
+// sniffFormat guesses the format name of a file.
+func sniffFormat(fname string, data []byte) string {
+	if strings.HasPrefix(filepath.Base(fname), ".env") {
+		return "env"
+	}
+
+	for _, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimSpace(l)
+		switch {
+		case l == "" || l[0] == '#' || l[0] == ';':
+			continue
+		case l[0] == '{':
+			return "json"
+		case l == "---":
+			return "yaml"
+		}
+
+		// a "key: value" line without '=' looks like YAML
+		if _, _, ok := splitYAMLKey(l); ok && !strings.Contains(l, "=") && l[0] != '[' {
+			return "yaml"
+		}
+		return "ini"
+	}
+
+	return "ini"
+}
+
+// setFilename sets the file name of the positions of the configuration.
+func (c *Config) setFilename(fname string) {
+	for _, options := range c.pos {
+		for option, pos := range options {
+			pos.Filename = fname
+			options[option] = pos
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.ini":        "host = example.com\n",
+		"a.toml":       `host = "example.com"`,
+		"a.json":       `{"default": {"host": "example.com"}}`,
+		"a.yml":        "host: example.com\n",
+		".env":         "HOST=example.com\n",
+		"a.properties": "host=example.com\n",
+		"a.unknown":    "\n{\"host\": \"example.com\"}",
+		"a.upper":      "# custom\n",
+	}
+
+	RegisterFormat("UPPER", func(reader io.Reader) (*Config, error) {
+		c := New()
+		c.AddOption("default", "host", "example.com")
+		return c, nil
+	}, nil)
+
+	for name, data := range files {
+		fname := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fname, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := Load(fname)
+		if err != nil {
+			t.Fatalf("Load(%q) returned error: %v", name, err)
+		}
+		ans, err := c.String("", "host")
+		verify(t, 0, "c.String", "", "host", ans, "example.com", err)
+	}
+
+	c, err := Load(filepath.Join(dir, "a.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos := c.Position("", "host"); pos.Filename != filepath.Join(dir, "a.ini") || pos.Line != 1 {
+		t.Fatalf("Load recorded position %v", pos)
+	}
+	for _, name := range []string{"a.ini", "a.json"} {
+		if _, err := LoadLimit(filepath.Join(dir, name), 8); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("LoadLimit(%q) returned %v, expected ErrTooLarge", name, err)
+		}
+		if _, err := ReadFileLimit(filepath.Join(dir, name), 8); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("ReadFileLimit(%q) returned %v, expected ErrTooLarge", name, err)
+		}
+	}
+	if _, err := LoadLimit(filepath.Join(dir, "a.ini"), 64); err != nil {
+		t.Errorf("LoadLimit returned %v below the maximum size", err)
+	}
+
+	for _, name := range []string{"b.json", "b.env", "b.properties", "b.conf"} {
+		fname := filepath.Join(dir, name)
+		if err := c.Save(fname, 0600, "header"); err != nil {
+			t.Fatalf("Save(%q) returned error: %v", name, err)
+		}
+		r, err := Load(fname)
+		if err != nil {
+			t.Fatalf("Load(%q) returned error: %v", name, err)
+		}
+		ans, err := r.String("", "host")
+		verify(t, 0, "r.String", "", "host", ans, "example.com", err)
+	}
+
+	if err := c.Save(filepath.Join(dir, "b.toml"), 0600, ""); err == nil {
+		t.Fatal("Save wrote format without writer")
+	}
+
+	RegisterFormat("yaml", nil, nil)
+	defer RegisterFormat("yaml", ReadYAML, nil)
+	fname := filepath.Join(dir, "a.xyz")
+	if err := ioutil.WriteFile(fname, []byte("host: example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(fname); err == nil {
+		t.Fatal("Load read format without reader")
+	}
+}
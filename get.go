@@ -7,55 +7,78 @@ import (
 	"encoding/csv"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Sections returns the list of sections in the configuration.
+// Sections returns the list of sections in the configuration, in the order
+// they were added. Names of subsections are hierarchical, e.g. "server.http"
+// (or "http" in the view returned by Sub("server")).
 // (The default section always exists.)
 func (c *Config) Sections() (sections []string) {
-	sections = make([]string, len(c.data))
-
-	i := 0
-	for s, _ := range c.data {
-		sections[i] = s
-		i++
+	keys := c.sectionKeys()
+	sections = make([]string, len(keys))
+	for i, key := range keys {
+		sections[i] = c.sectionName(key)
 	}
 
 	return sections
 }
 
+// Subsections returns the names of the subsections of the given section, in
+// the order they were added. A subsection is any section named
+// "section.subsection", whether it was read as [section "subsection"] or as
+// [section.subsection].
+func (c *Config) Subsections(section string) (subsections []string) {
+	parent := c.sectionKey(section)
+	for _, key := range c.sectionKeys() {
+		if strings.HasPrefix(key, parent+".") {
+			name := c.rootConfig().sectionName(key)
+			subsections = append(subsections, trimSegments(name, strings.Count(parent, ".")+1))
+		}
+	}
+
+	return subsections
+}
+
 // HasSection checks if the configuration has the given section.
 // (The default section always exists.)
 func (c *Config) HasSection(section string) bool {
-	if section == "" {
-		section = "default"
-	}
-	_, ok := c.data[strings.ToLower(section)]
+	_, ok := c.data[c.sectionKey(section)]
 
 	return ok
 }
 
-// Options returns the list of options available in the given section.
+// Options returns the list of options available in the given section,
+// in the order they were added.
 // It returns an error if the section does not exist and an empty list if the section is empty.
-// Options within the default section are also included.
+// Options within the default section are also included, as are options
+// inherited from parent sections (see RawString), each option only once.
 func (c *Config) Options(section string) (options []string, err error) {
-	if section == "" {
-		section = "default"
-	}
-	section = strings.ToLower(section)
+	section = c.sectionKey(section)
 
 	if _, ok := c.data[section]; !ok {
-		return nil, GetError{SectionNotFound, "", "", section, ""}
+		return nil, GetError{SectionNotFound, "", "", section, "", nil}
 	}
 
-	options = make([]string, len(c.data[DefaultSection])+len(c.data[section]))
-	i := 0
-	for s, _ := range c.data[DefaultSection] {
-		options[i] = s
-		i++
+	// default section first, then from the top-most parent down
+	keys := []string{section}
+	for key := c.parentKey(section); key != ""; key = c.parentKey(key) {
+		keys = append([]string{key}, keys...)
+	}
+	if def := c.sectionKey(DefaultSection); keys[0] != def {
+		keys = append([]string{def}, keys...)
 	}
-	for s, _ := range c.data[section] {
-		options[i] = s
-		i++
+
+	options = make([]string, 0)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		for _, option := range c.options[key] {
+			if !seen[option] {
+				seen[option] = true
+				options = append(options, c.optionName(key, option))
+			}
+		}
 	}
 
 	return options, nil
@@ -64,65 +87,254 @@ func (c *Config) Options(section string) (options []string, err error) {
 // HasOption checks if the configuration has the given option in the section.
 // It returns false if either the option or section do not exist.
 func (c *Config) HasOption(section string, option string) bool {
-	if section == "" {
-		section = "default"
-	}
-	section = strings.ToLower(section)
-	option = strings.ToLower(option)
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
 
 	if _, ok := c.data[section]; !ok {
 		return false
 	}
 
-	_, okd := c.data[DefaultSection][option]
-	_, oknd := c.data[section][option]
+	_, okd := c.data[c.sectionKey(DefaultSection)][option]
+	_, oknd := c.lookup(section, option)
 
 	return okd || oknd
 }
 
 // RawString gets the (raw) string value for the given option in the section.
 // The raw string value is not subjected to unfolding, which was illustrated in the beginning of this documentation.
+// Options missing from a subsection, e.g. [server.http], are inherited from
+// its parent sections, e.g. [server].
 // It returns an error if either the section or the option do not exist.
 func (c *Config) RawString(section string, option string) (value string, err error) {
-	if section == "" {
-		section = "default"
-	}
-
-	section = strings.ToLower(section)
-	option = strings.ToLower(option)
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
 
 	if _, ok := c.data[section]; ok {
-		if value, ok = c.data[section][option]; ok {
+		if value, ok = c.lookup(section, option); ok {
 			return value, nil
 		}
-		return "", GetError{OptionNotFound, "", "", section, option}
+		return "", GetError{OptionNotFound, "", "", section, option, nil}
+	}
+	return "", GetError{SectionNotFound, "", "", section, option, nil}
+}
+
+// Values gets all values of the given option in the section: the values of
+// a multi-valued option (see AppendOption) or the single value of other
+// options. Values are not unfolded.
+// It returns nil if either the section or the option do not exist.
+func (c *Config) Values(section string, option string) (values []string) {
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
+
+	if _, ok := c.data[section]; !ok {
+		return nil
+	}
+	for key := section; key != ""; key = c.parentKey(key) {
+		if multi, ok := c.values[key][option]; ok {
+			values = make([]string, len(multi))
+			copy(values, multi)
+			return values
+		}
+		if value, ok := c.data[key][option]; ok {
+			return []string{value}
+		}
 	}
-	return "", GetError{SectionNotFound, "", "", section, option}
+
+	return nil
+}
+
+// lookup gets the value of option in the section stored under key, or in
+// the nearest parent section which has it.
+func (c *Config) lookup(key string, option string) (value string, ok bool) {
+	if key, ok = c.lookupKey(key, option); ok {
+		return c.data[key][option], true
+	}
+	return "", false
+}
+
+// lookupKey returns the key of the section stored under key, or of its
+// nearest parent section, which has the option.
+func (c *Config) lookupKey(key string, option string) (string, bool) {
+	for ; key != ""; key = c.parentKey(key) {
+		if _, ok := c.data[key][option]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// IsFlag checks if the given option in the section is a flag, i.e. an
+// option without value (see AddFlag).
+// It returns false if either the option or section do not exist.
+func (c *Config) IsFlag(section string, option string) bool {
+	option = c.optionKey(option)
+
+	key, ok := c.lookupKey(c.sectionKey(section), option)
+	return ok && c.flags[key][option]
+}
+
+// HasValue checks if the given option in the section exists and has a value,
+// i.e. is not a flag.
+func (c *Config) HasValue(section string, option string) bool {
+	_, err := c.RawString(section, option)
+	return err == nil && !c.IsFlag(section, option)
 }
 
 // String gets the string value for the given option in the section.
-// If the value needs to be unfolded (see e.g. %(host)s example in the beginning of this documentation),
-// then String does this unfolding automatically, up to DepthValues number of iterations.
-// It returns an error if either the section or the option do not exist, or the unfolding cycled.
+// The value is passed through the transformers of the configuration (see
+// Use), e.g. Unfold to unfold variables like %(host)s.
+// It returns an error if either the section or the option do not exist, or
+// a GetError with reason CouldNotTransform if a transformer failed (the
+// unfolding cycled, a secret could not be resolved, etc.).
 func (c *Config) String(section string, option string) (value string, err error) {
 	value, err = c.RawString(section, option)
 	if err != nil {
 		return "", err
 	}
 
+	return c.transformValue(section, option, value)
+}
+
+// transformValue passes the value of the given option in the section
+// through the transformers of the configuration.
+func (c *Config) transformValue(section string, option string, value string) (string, error) {
+	var err error
+	for _, t := range c.transform {
+		if value, err = t(c, section, option, value); err != nil {
+			if _, ok := err.(GetError); ok {
+				return "", err
+			}
+			return "", GetError{CouldNotTransform, "", "", section, option, err}
+		}
+	}
+
 	return value, nil
 }
 
+// Unfold is a ValueTransformer replacing the variables like %(host)s in value
+// by the value of the named option, looked up in the section of c and then
+// in the default section. The values substituted are unfolded in turn, up to
+// DepthValues times. Use it with Use:
+//
+//	c.Use(conf.Unfold)
+//
+// It returns a GetError with reason MaxDepthReached if the unfolding cycled.
+func Unfold(c *Config, section string, option string, value string) (string, error) {
+	for i := 0; i < DepthValues; i++ {
+		vr := varRegExp.FindStringSubmatchIndex(value)
+		if len(vr) == 0 {
+			return value, nil
+		}
+
+		name := value[vr[2]:vr[3]]
+		nvalue, err := c.RawString(section, name)
+		if err != nil {
+			nvalue, _ = c.RawString(DefaultSection, name)
+		}
+		value = value[:vr[0]] + nvalue + value[vr[1]:]
+	}
+
+	return "", GetError{MaxDepthReached, "", "", section, option, nil}
+}
+
 // StringList gets the string values for the given option in the section.
+// The value is split according to the list options of the configuration
+// (see SetListOptions).
 // It returns an error if either the section or the option do not exist,
-// or the unfolding cycled.
+// a transformer failed, or a GetError with reason CouldNotParse if the value
+// is not a valid list (e.g. has an unterminated quote).
 func (c *Config) StringList(section string, option string) (values []string, err error) {
-	value, err := c.RawString(section, option)
+	return c.StringListOpts(section, option, c.list)
+}
+
+// StringListOpts has the same behaviour as StringList but splits the value
+// according to opts instead of the list options of the configuration.
+func (c *Config) StringListOpts(section string, option string, opts ListOptions) (values []string, err error) {
+	value, err := c.String(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	if values, err = splitList(value, opts); err != nil {
+		return nil, GetError{CouldNotParse, "list", value, section, option, err}
+	}
+	return values, nil
+}
+
+// StringMap gets the key/value pairs for the given option in the section.
+// Items are separated as in StringList and each item is split into key and
+// value on the first '='.
+// It returns an error if either the section or the option do not exist,
+// or an item has no key/value separator.
+func (c *Config) StringMap(section string, option string) (values map[string]string, err error) {
+	return c.StringMapSep(section, option, c.list.separator(), '=')
+}
+
+// StringMapSep has the same behaviour as StringMap but uses itemSep to
+// separate items on a single line and pairSep to separate keys from values.
+func (c *Config) StringMapSep(section string, option string, itemSep, pairSep rune) (values map[string]string, err error) {
+	value, err := c.String(section, option)
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.Contains(value, "\n") {
+	values = make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return values, nil
+	}
+
+	opts := c.list
+	opts.Separator = itemSep
+	items, err := splitList(value, opts)
+	if err != nil {
+		return nil, GetError{CouldNotParse, "list", value, section, option, err}
+	}
+
+	for _, item := range items {
+		if item == "" {
+			continue
+		}
+		i := strings.IndexRune(item, pairSep)
+		if i <= 0 {
+			return nil, GetError{CouldNotParse, "map", item, section, option, nil}
+		}
+		key := strings.Trim(item[:i], " \t\r\n")
+		values[key] = strings.Trim(item[i+utf8.RuneLen(pairSep):], " \t\r\n")
+	}
+
+	return values, nil
+}
+
+// splitList splits a list value into its items. Multi-line values have one
+// item per line, other values are parsed as a single CSV record using the
+// separator from opts. Bracketed lists are parsed as CSV records per line.
+func splitList(value string, opts ListOptions) (values []string, err error) {
+	sep := opts.separator()
+
+	bracketed := false
+	if opts.Brackets {
+		if v := strings.TrimSpace(value); len(v) >= 2 && v[0] == '[' && v[len(v)-1] == ']' {
+			value = v[1 : len(v)-1]
+			bracketed = true
+		}
+	}
+
+	switch {
+	case bracketed:
+		for _, line := range strings.Split(value, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			line = strings.TrimSuffix(line, string(sep)) // allow trailing separator
+			items, err := splitRecord(line, sep)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, items...)
+		}
+
+	case strings.Contains(value, "\n"):
 		v := strings.Split(value, "\n")
 		if len(v[0]) == 0 {
 			// Remove first empty element
@@ -133,16 +345,48 @@ func (c *Config) StringList(section string, option string) (values []string, err
 		for _, val := range v {
 			values = append(values, strings.Trim(val, " \t\r\n"))
 		}
-	} else {
-		v := strings.NewReader(value)
-		csvData := csv.NewReader(v)
-		values, err = csvData.Read()
+
+	default:
+		values, err = splitRecord(value, sep)
 		if err != nil {
 			return nil, err
 		}
-		for i, val := range values {
-			values[i] = strings.Trim(val, " \t\r\n")
+	}
+
+	// Runs of white space separate a single item.
+	if opts.SkipEmpty || unicode.IsSpace(sep) {
+		items := values[:0]
+		for _, val := range values {
+			if val != "" {
+				items = append(items, val)
+			}
 		}
+		values = items
+	}
+
+	return values, nil
+}
+
+// joinList returns items in the multi-line form split by splitList.
+func joinList(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(items, "\n")
+}
+
+// splitRecord parses a single line as a CSV record separated by sep.
+func splitRecord(value string, sep rune) (values []string, err error) {
+	v := strings.NewReader(value)
+	csvData := csv.NewReader(v)
+	csvData.Comma = sep
+	csvData.TrimLeadingSpace = true
+	values, err = csvData.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, val := range values {
+		values[i] = strings.Trim(val, " \t\r\n")
 	}
 
 	return values, nil
@@ -154,7 +398,7 @@ func (c *Config) Int(section string, option string) (value int, err error) {
 	if err == nil {
 		value, err = strconv.Atoi(sv)
 		if err != nil {
-			err = GetError{CouldNotParse, "int", sv, section, option}
+			err = GetError{CouldNotParse, "int", sv, section, option, err}
 		}
 	}
 
@@ -172,7 +416,7 @@ func (c *Config) IntList(section string, option string) (values []int, err error
 	for _, val := range slvs {
 		value, err := strconv.Atoi(val)
 		if err != nil {
-			err = GetError{CouldNotParse, "int", val, section, option}
+			err = GetError{CouldNotParse, "int", val, section, option, err}
 			return nil, err
 		}
 		values = append(values, value)
@@ -188,7 +432,7 @@ func (c *Config) Int64(section string, option string) (value int64, err error) {
 	if err == nil {
 		value, err = strconv.ParseInt(sv, 10, 64)
 		if err != nil {
-			err = GetError{CouldNotParse, "int64", sv, section, option}
+			err = GetError{CouldNotParse, "int64", sv, section, option, err}
 		}
 	}
 
@@ -206,7 +450,7 @@ func (c *Config) Int64List(section string, option string) (values []int64, err e
 	for _, val := range slvs {
 		value, err := strconv.ParseInt(val, 10, 64)
 		if err != nil {
-			err = GetError{CouldNotParse, "int64", val, section, option}
+			err = GetError{CouldNotParse, "int64", val, section, option, err}
 			return nil, err
 		}
 		values = append(values, value)
@@ -222,7 +466,7 @@ func (c *Config) Float64(section string, option string) (value float64, err erro
 	if err == nil {
 		value, err = strconv.ParseFloat(sv, 64)
 		if err != nil {
-			err = GetError{CouldNotParse, "float64", sv, section, option}
+			err = GetError{CouldNotParse, "float64", sv, section, option, err}
 		}
 	}
 
@@ -240,8 +484,7 @@ func (c *Config) Float64List(section string, option string) (values []float64, e
 	for _, val := range slvs {
 		value, err := strconv.ParseFloat(val, 64)
 		if err != nil {
-			print(err.Error())
-			err = GetError{CouldNotParse, "float64", val, section, option}
+			err = GetError{CouldNotParse, "float64", val, section, option, err}
 			return nil, err
 		}
 		values = append(values, value)
@@ -251,16 +494,16 @@ func (c *Config) Float64List(section string, option string) (values []float64, e
 }
 
 // Bool has the same behaviour as String but converts the response to bool.
-// See constant BoolStrings for string values converted to bool.
+// See SetBoolStrings and SetStrictBool for string values converted to bool.
 func (c *Config) Bool(section string, option string) (value bool, err error) {
 	sv, err := c.String(section, option)
 	if err != nil {
 		return false, err
 	}
 
-	value, ok := BoolStrings[strings.ToLower(sv)]
+	value, ok := c.parseBool(sv)
 	if !ok {
-		return false, GetError{CouldNotParse, "bool", sv, section, option}
+		return false, GetError{CouldNotParse, "bool", sv, section, option, nil}
 	}
 
 	return value, nil
@@ -275,9 +518,9 @@ func (c *Config) BoolList(section string, option string) (values []bool, err err
 	}
 
 	for _, val := range slvs {
-		value, ok := BoolStrings[strings.ToLower(val)]
+		value, ok := c.parseBool(val)
 		if !ok {
-			err = GetError{CouldNotParse, "int64", val, section, option}
+			err = GetError{CouldNotParse, "bool", val, section, option, nil}
 			return nil, err
 		}
 		values = append(values, value)
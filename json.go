@@ -0,0 +1,264 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ReadJSON reads a JSON object and returns a new configuration representation.
+// Members holding objects become sections (nested objects become sections
+// named "section.subsection") and other members become options of the
+// default section. Numbers and booleans are stored as written, arrays are
+// stored in the multi-line form understood by StringList and null is stored
+// as the empty string. It reads the output of both MarshalJSON and TypedJSON.
+func ReadJSON(reader io.Reader) (c *Config, err error) {
+	c = New()
+	if err = c.readJSON(reader); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// MarshalJSON implements json.Marshaler. The configuration is encoded as an
+// object holding an object per section, which holds the options as strings.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	return c.marshalJSON(func(section, option string) (interface{}, error) {
+		return c.RawString(section, option)
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It replaces the sections and
+// options of the configuration (or view) with the ones in data, as read by
+// ReadJSON, keeping the settings of the configuration.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	n := New()
+	n.caseMode = c.caseMode
+	if err := n.readJSON(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	if c.data == nil {
+		*c = *n
+		return nil
+	}
+
+	for _, key := range append([]string(nil), c.sectionKeys()...) {
+		section := c.sectionName(key)
+		if key != c.sectionKey(DefaultSection) {
+			c.RemoveSection(section)
+			continue
+		}
+		for _, option := range append([]string(nil), c.options[key]...) {
+			c.RemoveOption(section, option)
+		}
+	}
+	for _, key := range n.sections {
+		section := n.sectionName(key)
+		c.AddSection(section)
+		for _, option := range n.options[key] {
+			c.AddOption(section, n.optionName(key, option), n.data[key][option])
+		}
+	}
+
+	return nil
+}
+
+// TypedJSON encodes the configuration like MarshalJSON, but values which
+// parse as int64, float64 or bool (in that order, using the rules of the
+// typed getters) are encoded as numbers and booleans, and values holding
+// more than one item according to StringList are encoded as arrays of such
+// values. Values of secret options are redacted and encrypted values are
+// encoded as they are, so that no decrypted value or resolved secret is
+// exported.
+func (c *Config) TypedJSON() ([]byte, error) {
+	return c.marshalJSON(func(section, option string) (interface{}, error) {
+		raw, err := c.RawString(section, option)
+		switch {
+		case err != nil:
+			return nil, err
+		case c.IsSecret(section, option):
+			return redact(raw), nil
+		case IsEncrypted(raw):
+			return raw, nil
+		}
+
+		values, err := c.StringList(section, option)
+		if err == nil && len(values) > 1 {
+			typed := make([]interface{}, len(values))
+			for i, v := range values {
+				typed[i] = c.typedValue(v)
+			}
+			return typed, nil
+		}
+
+		value, err := c.String(section, option)
+		if err != nil {
+			return nil, err
+		}
+		return c.typedValue(value), nil
+	})
+}
+
+// typedValue converts value to the first JSON type it parses as.
+func (c *Config) typedValue(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+		return v
+	}
+	if v, ok := c.parseBool(value); ok {
+		return v
+	}
+	return value
+}
+
+// marshalJSON encodes the sections and options in order, using value to get
+// the value of each option.
+func (c *Config) marshalJSON(value func(section, option string) (interface{}, error)) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+
+	buf.WriteByte('{')
+	first := true
+	for _, section := range c.sectionKeys() {
+		if section == c.sectionKey(DefaultSection) && len(c.data[section]) == 0 {
+			continue // skip default section if empty
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		writeJSON(buf, c.sectionName(section))
+		buf.WriteString(":{")
+		for i, option := range c.options[section] {
+			v, err := value(c.sectionName(section), option)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, c.optionName(section, option))
+			buf.WriteByte(':')
+			if err = writeJSON(buf, v); err != nil {
+				return nil, err
+			}
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// readJSON adds the sections and options of the JSON object read from reader,
+// keeping their order.
+func (c *Config) readJSON(reader io.Reader) (err error) {
+	dec := json.NewDecoder(reader)
+	dec.UseNumber()
+
+	if err = expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	if err = c.readJSONObject(dec, DefaultSection, ""); err != nil {
+		return err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return fmt.Errorf("conf: unexpected data after JSON object")
+	}
+
+	return nil
+}
+
+// readJSONObject reads the members of an object whose opening brace has
+// been read. Options are added to section, and objects become sections
+// named prefix + member name.
+func (c *Config) readJSONObject(dec *json.Decoder, section string, prefix string) error {
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := t.(string) // object keys are always strings
+
+		t, err = dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case json.Delim('{'):
+			c.AddSection(prefix + name)
+			if err = c.readJSONObject(dec, prefix+name, prefix+name+"."); err != nil {
+				return err
+			}
+
+		case json.Delim('['):
+			var items []string
+			for dec.More() {
+				t, err = dec.Token()
+				if err != nil {
+					return err
+				}
+				item, ok := jsonScalar(t)
+				if !ok {
+					return fmt.Errorf("conf: JSON array %q may only hold strings, numbers and booleans", name)
+				}
+				items = append(items, item)
+			}
+			if err = expectDelim(dec, ']'); err != nil {
+				return err
+			}
+			c.AddOption(section, name, joinList(items))
+
+		default:
+			value, _ := jsonScalar(t)
+			c.AddOption(section, name, value)
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// jsonScalar converts a scalar JSON token to its string value.
+func jsonScalar(t json.Token) (value string, ok bool) {
+	switch v := t.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "", true
+	}
+	return "", false
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("conf: expected %v in JSON, got %v", delim, t)
+	}
+	return nil
+}
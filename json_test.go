@@ -0,0 +1,89 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+const jsonConfFile = `
+[default]
+host = example.com
+port = 443
+
+[service-1]
+active = on
+ratio = 0.5
+list = 1, 2, 3
+`
+
+func TestJSON(t *testing.T) {
+	c, err := ReadBytes([]byte(jsonConfFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		marshal func() ([]byte, error)
+		answer  string
+	}{
+		{c.MarshalJSON, `{"default":{"host":"example.com","port":"443"},"service-1":{"active":"on","ratio":"0.5","list":"1, 2, 3"}}`},
+		{c.TypedJSON, `{"default":{"host":"example.com","port":443},"service-1":{"active":true,"ratio":0.5,"list":[1,2,3]}}`},
+	}
+
+	for testnum, e := range tests {
+		data, err := e.marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != e.answer {
+			t.Fatalf("%d. JSON %s != %s", testnum, data, e.answer)
+		}
+
+		r, err := ReadJSON(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ans, err := r.IntList("service-1", "list")
+		verifyList(t, testnum, "r.IntList", "service-1", "list", ans, []int{1, 2, 3}, err)
+		b, err := r.Bool("service-1", "active")
+		verify(t, testnum, "r.Bool", "service-1", "active", b, true, err)
+	}
+
+	var u Config
+	if err := json.Unmarshal([]byte(`{"a":{"b":{"c":"d"}}, "e": null}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	ans, err := u.String("a.b", "c")
+	verify(t, 0, "u.String", "a.b", "c", ans, "d", err)
+	ans, err = u.String("", "e")
+	verify(t, 1, "u.String", "", "e", ans, "", err)
+
+	// settings are kept
+	s := New()
+	s.SetCaseMode(CaseSensitive)
+	s.SetSecret("S", "Key", true)
+	s.AddOption("old", "x", "y")
+	s.AddOption("older", "x", "y")
+	if err := json.Unmarshal([]byte(`{"S":{"Key":"v"}}`), s); err != nil {
+		t.Fatal(err)
+	}
+	ans, err = s.String("S", "Key")
+	verify(t, 2, "s.String", "S", "Key", ans, "v", err)
+	if s.HasSection("s") || s.HasSection("old") || s.HasSection("older") || !s.IsSecret("S", "Key") {
+		t.Errorf("json.Unmarshal did not keep the settings: sections %v", s.Sections())
+	}
+
+	// a view only replaces its sections
+	v := s.Sub("S")
+	if err := json.Unmarshal([]byte(`{"Key":"w","T":{"a":"b"}}`), v); err != nil {
+		t.Fatal(err)
+	}
+	ans, err = s.String("S.T", "a")
+	verify(t, 3, "s.String", "S.T", "a", ans, "b", err)
+	ans, err = v.String("", "Key")
+	verify(t, 4, "v.String", "", "Key", ans, "w", err)
+}
@@ -0,0 +1,104 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Conflict describes a change that Apply could not apply because the
+// configuration does not have the value expected by the change.
+type Conflict struct {
+	Change  Change // The change that was not applied.
+	Current string // Current (last) value of the option (empty if it does not exist).
+}
+
+func (cf Conflict) String() string {
+	return fmt.Sprintf("conflict: %s (current %q)", cf.Change, cf.Current)
+}
+
+// Apply applies changes, as returned by Diff, to the configuration.
+// A change conflicts if the current value of its option is neither the old
+// value nor already the new value of the change: an option to add already
+// exists with another value, an option to remove or modify has another value
+// or an option to modify does not exist. All values of multi-valued options
+// are compared and set. A section to remove conflicts if it
+// still has options once the other changes have been applied.
+// Conflicting changes are not applied and are returned in order.
+func (c *Config) Apply(changes []Change) (conflicts []Conflict) {
+	var removed []Change
+	for _, ch := range changes {
+		key, option := c.sectionKey(ch.Section), c.optionKey(ch.Option)
+		current, ok := c.data[key][option]
+		values, multi := c.values[key][option]
+		if !multi {
+			values = []string{current}
+		}
+
+		switch ch.Type {
+		case SectionAdded:
+			c.AddSection(ch.Section)
+
+		case SectionRemoved:
+			removed = append(removed, ch) // once its options are removed
+
+		case OptionAdded:
+			switch {
+			case !ok:
+				c.setValues(ch.Section, ch.Option, ch.newValues())
+			case !reflect.DeepEqual(values, ch.newValues()):
+				conflicts = append(conflicts, Conflict{ch, current})
+			}
+
+		case OptionRemoved:
+			switch {
+			case ok && reflect.DeepEqual(values, ch.oldValues()):
+				c.RemoveOption(ch.Section, ch.Option)
+			case ok:
+				conflicts = append(conflicts, Conflict{ch, current})
+			}
+
+		case OptionModified:
+			switch {
+			case ok && reflect.DeepEqual(values, ch.oldValues()):
+				c.setValues(ch.Section, ch.Option, ch.newValues())
+			case !ok || !reflect.DeepEqual(values, ch.newValues()):
+				conflicts = append(conflicts, Conflict{ch, current})
+			}
+		}
+	}
+
+	for _, ch := range removed {
+		if !c.HasSection(ch.Section) {
+			continue
+		}
+		if len(c.options[c.sectionKey(ch.Section)]) > 0 {
+			conflicts = append(conflicts, Conflict{ch, ""})
+			continue
+		}
+		c.RemoveSection(ch.Section)
+	}
+
+	return conflicts
+}
+
+// setValues replaces the values of the given option in the section.
+func (c *Config) setValues(section string, option string, values []string) {
+	c.AddOption(section, option, values[0])
+	for _, value := range values[1:] {
+		c.AppendOption(section, option, value)
+	}
+}
+
+// Merge3 merges the changes made from base to theirs into ours, returning
+// the merged configuration and the conflicting changes, which are not
+// merged (see Apply). Options changed the same way in ours and theirs do not
+// conflict. None of the configurations is modified.
+func Merge3(base, ours, theirs *Config) (merged *Config, conflicts []Conflict) {
+	merged = ours.Clone()
+	conflicts = merged.Apply(Diff(base, theirs))
+
+	return merged, conflicts
+}
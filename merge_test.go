@@ -0,0 +1,62 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	c, err := ReadBytes([]byte("[db]\nhost = a\nport = 3\nuser = x\n[old]\nk = v\n[keep]\nk = v\nextra = 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []Change{
+		{OptionModified, "db", "host", "a", "b", false, nil, nil},
+		{OptionModified, "db", "port", "1", "2", false, nil, nil},
+		{OptionRemoved, "db", "user", "x", "", false, nil, nil},
+		{OptionAdded, "db", "pass", "", "p", false, nil, nil},
+		{SectionRemoved, "old", "", "", "", false, nil, nil},
+		{OptionRemoved, "old", "k", "v", "", false, nil, nil},
+		{SectionRemoved, "keep", "", "", "", false, nil, nil},
+		{OptionRemoved, "keep", "k", "v", "", false, nil, nil},
+		{SectionAdded, "new", "", "", "", false, nil, nil},
+	}
+	conflicts := c.Apply(changes)
+	expected := []Conflict{{changes[1], "3"}, {changes[6], ""}}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Fatalf("c.Apply returned %v, expected %v", conflicts, expected)
+	}
+
+	sections := c.Sections()
+	verifyList(t, 0, "c.Sections", "", "", sections, []string{"default", "db", "keep", "new"}, nil)
+	options, err := c.Options("db")
+	verifyList(t, 1, "c.Options", "db", "", options, []string{"host", "port", "pass"}, err)
+	ans, err := c.String("db", "host")
+	verify(t, 2, "c.String", "db", "host", ans, "b", err)
+}
+
+func TestMerge3(t *testing.T) {
+	base, _ := ReadBytes([]byte("[a]\nx = 1\ny = 1\nz = 1\n"))
+	ours, _ := ReadBytes([]byte("[a]\nx = 2\ny = 1\nz = 2\n"))
+	theirs, _ := ReadBytes([]byte("[a]\nx = 1\ny = 3\nz = 3\n[b]\nw = 1\n"))
+
+	merged, conflicts := Merge3(base, ours, theirs)
+	expected := []Conflict{{Change{OptionModified, "a", "z", "1", "3", false, nil, nil}, "2"}}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Fatalf("Merge3 returned %v, expected %v", conflicts, expected)
+	}
+
+	for _, e := range []struct{ section, option, value string }{
+		{"a", "x", "2"}, {"a", "y", "3"}, {"a", "z", "2"}, {"b", "w", "1"},
+	} {
+		ans, err := merged.String(e.section, e.option)
+		verify(t, 0, "merged.String", e.section, e.option, ans, e.value, err)
+	}
+	if ours.HasSection("b") {
+		t.Error("Merge3 modified ours")
+	}
+}
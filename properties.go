@@ -0,0 +1,221 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ReadProperties reads a Java .properties file and returns a new
+// configuration representation. Keys are split on their last dot into
+// section and option ("db.primary.host" is option "host" of section
+// "db.primary"); keys without a dot are options of the default section.
+// Escapes and continuation lines are handled as by java.util.Properties,
+// except that the input is read as UTF-8.
+func ReadProperties(reader io.Reader) (c *Config, err error) {
+	c = New()
+	if err = c.readProperties(reader); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Config) readProperties(reader io.Reader) (err error) {
+	buf := bufio.NewReader(reader)
+
+	pos := Position{}
+	logical := ""
+	for {
+		l, buferr := buf.ReadString('\n')
+		if buferr != nil && buferr != io.EOF {
+			return buferr
+		}
+		pos.Line++
+		l = strings.TrimRight(l, "\r\n")
+
+		if logical == "" {
+			l = strings.TrimLeft(l, " \t\f")
+			if l == "" || l[0] == '#' || l[0] == '!' {
+				if buferr == io.EOF {
+					return nil
+				}
+				continue
+			}
+		} else {
+			l = strings.TrimLeft(l, " \t\f") // continuation lines drop leading space
+		}
+
+		// an odd number of trailing backslashes continues the line
+		n := len(l) - len(strings.TrimRight(l, "\\"))
+		if n%2 == 1 && buferr == nil {
+			logical += l[:len(l)-1]
+			continue
+		}
+		logical += l
+
+		key, value, err := splitProperty(logical)
+		if err != nil {
+			return err
+		}
+		section, option := DefaultSection, key
+		if i := strings.LastIndex(key, "."); i > 0 && i < len(key)-1 {
+			section, option = key[:i], key[i+1:]
+		}
+		c.AddOption(section, option, value)
+		c.setPosition(section, option, pos)
+		logical = ""
+
+		if buferr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// splitProperty splits a logical line into its unescaped key and value.
+func splitProperty(l string) (key string, value string, err error) {
+	i := 0
+	for i < len(l) && strings.IndexByte("=: \t\f", l[i]) < 0 {
+		if l[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i > len(l) {
+		i = len(l)
+	}
+	if key, err = unescapeProperty(l[:i]); err != nil {
+		return "", "", err
+	}
+
+	rest := strings.TrimLeft(l[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	if value, err = unescapeProperty(rest); err != nil {
+		return "", "", err
+	}
+
+	return key, value, nil
+}
+
+// unescapeProperty replaces the escape sequences of a key or value.
+func unescapeProperty(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", ReadError{CouldNotParse, s}
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", ReadError{CouldNotParse, s}
+			}
+			i += 4
+			// non-BMP characters are written as a surrogate pair
+			if utf16.IsSurrogate(rune(r)) && i+6 < len(s) && s[i+1:i+3] == `\u` {
+				if r2, err := strconv.ParseUint(s[i+3:i+7], 16, 16); err == nil {
+					if pair := utf16.DecodeRune(rune(r), rune(r2)); pair != utf8.RuneError {
+						r = uint64(pair)
+						i += 6
+					}
+				}
+			}
+			buf.WriteRune(rune(r))
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// WriteProperties writes the configuration to the io.Writer in the Java
+// .properties format. Options of the default section are written as plain
+// keys, other options as "section.option" keys.
+// The header is written as a comment in the first line.
+func (c *Config) WriteProperties(writer io.Writer, header string) (err error) {
+	buf := bytes.NewBuffer(nil)
+
+	if header != "" {
+		if _, err = buf.WriteString("# " + header + "\n"); err != nil {
+			return err
+		}
+	}
+
+	for _, section := range c.sectionKeys() {
+		prefix := c.sectionName(section) + "."
+		if section == c.sectionKey(DefaultSection) {
+			prefix = ""
+		}
+		for _, option := range c.options[section] {
+			if err = writeComment(buf, c.comments[section][option]); err != nil {
+				return err
+			}
+			key := escapeProperty(prefix+c.optionName(section, option), true)
+			value := escapeProperty(c.data[section][option], false)
+			if _, err = buf.WriteString(key + "=" + value + "\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = buf.WriteTo(writer)
+
+	return err
+}
+
+// escapeProperty escapes a key or value for a .properties file.
+func escapeProperty(s string, key bool) string {
+	buf := bytes.NewBuffer(nil)
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			buf.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r), i == 0 && (r == '#' || r == '!'):
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r < ' ' || r == utf8.RuneError:
+			buf.WriteString(`\u` + strconv.FormatInt(int64(r)|0x10000, 16)[1:])
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
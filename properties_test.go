@@ -0,0 +1,52 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const propertiesFile = `# Comment
+! Comment
+host = example.com
+port:443
+service-1.active true
+service-1.ratio = 1000.5
+service-1.list = 1, \
+                 2, 3
+service-1.names = \none,one\n\
+	two\tthree
+key\ with\=escapes = café
+`
+
+func TestProperties(t *testing.T) {
+	c, err := ReadProperties(strings.NewReader(propertiesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkFormatSet(t, c)
+	ans, err := c.String("", "key with=escapes")
+	verify(t, 0, "c.String", "", "key with=escapes", ans, "café", err)
+
+	buf := bytes.NewBuffer(nil)
+	if err := c.WriteProperties(buf, ""); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ReadProperties(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFormatSet(t, r)
+	ans, err = r.String("", "key with=escapes")
+	verify(t, 1, "r.String", "", "key with=escapes", ans, "café", err)
+
+	if c, err = ReadProperties(strings.NewReader(`smiley = \uD83D\uDE00\u00e9`)); err != nil {
+		t.Fatal(err)
+	}
+	ans, err = c.String("", "smiley")
+	verify(t, 2, "c.String", "", "smiley", ans, "\U0001F600é", err)
+}
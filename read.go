@@ -4,8 +4,8 @@
 package conf
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"strings"
@@ -14,6 +14,12 @@ import (
 // ReadFile reads a file and returns a new configuration representation.
 // This representation can be queried with String, etc.
 func ReadFile(fname string) (c *Config, err error) {
+	return ReadFileLimit(fname, 0)
+}
+
+// ReadFileLimit has the same behaviour as ReadFile but fails with
+// ErrTooLarge if the file has more than n bytes (0 for no limit).
+func ReadFileLimit(fname string, n int64) (c *Config, err error) {
 	var file *os.File
 
 	if file, err = os.Open(fname); err != nil {
@@ -21,7 +27,9 @@ func ReadFile(fname string) (c *Config, err error) {
 	}
 
 	c = New()
-	if err = c.Read(file); err != nil {
+	c.SetMaxSize(n)
+	if err = c.read(file, fname); err != nil {
+		file.Close()
 		return nil, err
 	}
 
@@ -45,72 +53,144 @@ func ReadBytes(conf []byte) (c *Config, err error) {
 
 // Read reads an io.Reader and returns a configuration representation. This
 // representation can be queried with String, etc.
+// It returns ErrTooLarge if the reader has more than the maximum size set
+// with SetMaxSize.
 func (c *Config) Read(reader io.Reader) (err error) {
-	buf := bufio.NewReader(reader)
+	return c.read(reader, "")
+}
 
-	var section, option string
-	section = "default"
-	for {
-		l, buferr := buf.ReadString('\n') // parse line-by-line
-		l = strings.TrimSpace(l)
+// ReadContext has the same behaviour as Read but aborts with the error of
+// ctx once it is cancelled or its deadline is exceeded. The context is
+// checked before each read from the reader.
+func (c *Config) ReadContext(ctx context.Context, reader io.Reader) (err error) {
+	return c.read(&contextReader{ctx, reader}, "")
+}
 
-		if buferr != nil {
-			if buferr != io.EOF {
-				return err
-			}
+// contextReader is an io.Reader which fails once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
 
-			if len(l) == 0 {
-				break
-			}
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// LimitReader returns an io.Reader reading from r which fails with
+// ErrTooLarge once more than n bytes were read, to limit the input of the
+// readers taking an io.Reader, e.g.
+//
+//	c, err := conf.ReadJSON(conf.LimitReader(reader, 1<<20))
+//
+// Unlike io.LimitReader, it does not silently truncate the input.
+func LimitReader(r io.Reader, n int64) io.Reader {
+	return &limitReader{r, 0, n}
+}
+
+// limitReader is the io.Reader returned by LimitReader.
+type limitReader struct {
+	r    io.Reader
+	read int64
+	max  int64
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if r.read += int64(n); r.read > r.max {
+		// do not pass on the bytes beyond the limit
+		n -= int(r.read - r.max)
+		r.read = r.max + 1
+		if n < 0 {
+			n = 0
 		}
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// read reads the configuration from reader, recording positions in fname.
+func (c *Config) read(reader io.Reader, fname string) (err error) {
+	if c.maxSize > 0 {
+		reader = LimitReader(reader, c.maxSize)
+	}
+	s := newScanner(reader, fname)
+	s.SetAllowNoValue(c.noValue)
+
+	section := DefaultSection
+	for s.Scan() {
+		e := s.Event()
 
-		// switch written for readability (not performance)
 		switch {
-		case len(l) == 0: // empty line
+		case e.Type == CommentEvent:
 			continue
 
-		case l[0] == '#': // comment
-			continue
+		case e.Type == SectionEvent:
+			section = e.Section
+			if e.Subsection != "" {
+				c.AddSubsection(e.Section, e.Subsection)
+				section = c.sectionKey(e.Section) + "." + e.Subsection
+			} else {
+				c.AddSection(section)
+			}
+			c.setPosition(section, "", e.Pos)
 
-		case l[0] == ';': // comment
-			continue
+		case section == "": // not new section and no section defined so far
+			return ReadError{BlankSection, e.Raw}
 
-		case len(l) >= 3 && strings.ToLower(l[0:3]) == "rem": // comment (for windows users)
-			continue
+		case e.Type == ContinuationEvent:
+			prev, _ := c.RawString(section, e.Option)
+			c.setLastValue(section, e.Option, prev+"\n"+e.Value)
 
-		case l[0] == '[' && l[len(l)-1] == ']': // new section
-			option = "" // reset multi-line value
-			section = strings.TrimSpace(l[1 : len(l)-1])
-			c.AddSection(section)
+		case e.Flag:
+			c.AddFlag(section, e.Option)
+			c.setPosition(section, e.Option, e.Pos)
 
-		case section == "": // not new section and no section defined so far
-			return ReadError{BlankSection, l}
-
-		default: // other alternatives
-			i := strings.IndexAny(l, "=:")
-			switch {
-			case i > 0: // option and value
-				i := strings.IndexAny(l, "=:")
-				option = strings.TrimSpace(l[0:i])
-				value := strings.TrimSpace(stripComments(l[i+1:]))
-				c.AddOption(section, option, value)
-
-			case section != "" && option != "": // continuation of multi-line value
-				prev, _ := c.RawString(section, option)
-				value := strings.TrimSpace(stripComments(l))
-				c.AddOption(section, option, prev+"\n"+value)
-
-			default:
-				return ReadError{CouldNotParse, l}
-			}
+		case c.multi:
+			c.AppendOption(section, e.Option, e.Value)
+			c.setPosition(section, e.Option, e.Pos)
+
+		default:
+			c.AddOption(section, e.Option, e.Value)
+			c.setPosition(section, e.Option, e.Pos)
 		}
+	}
+
+	return s.Err()
+}
 
-		// Reached end of file
-		if buferr == io.EOF {
-			break
+// splitSubsection splits a git-style section name `section "subsection"`.
+// Backslashes in the quoted subsection name escape the following character.
+func splitSubsection(s string) (section string, subsection string, ok bool) {
+	i := strings.IndexAny(s, " \t")
+	if i <= 0 || !strings.HasSuffix(s, `"`) {
+		return "", "", false
+	}
+	section = s[:i]
+	q := strings.TrimLeft(s[i:], " \t")
+	if len(q) < 2 || q[0] != '"' {
+		return "", "", false
+	}
+
+	buf := make([]byte, 0, len(q))
+	for j := 1; j < len(q); j++ {
+		switch q[j] {
+		case '\\':
+			if j++; j == len(q) {
+				return "", "", false
+			}
+		case '"':
+			if j != len(q)-1 {
+				return "", "", false
+			}
+			return section, string(buf), true
 		}
+		buf = append(buf, q[j])
 	}
-	return nil
+
+	return "", "", false
 }
 
 func stripComments(l string) string {
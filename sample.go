@@ -0,0 +1,216 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Sample returns a configuration with every section and option declared in
+// the schema, set to its default value, and commented with its description,
+// type, default and allowed values. Write it to get a reference file.
+func (s *Schema) Sample() *Config {
+	c := New()
+
+	for _, ss := range s.Sections {
+		c.AddSection(ss.Name)
+		c.SetComment(ss.Name, "", ss.Help)
+
+		for _, opt := range ss.Options {
+			c.AddOption(ss.Name, opt.Name, opt.Default)
+			c.SetComment(ss.Name, opt.Name, opt.comment())
+		}
+	}
+
+	return c
+}
+
+// WriteSample writes the sample configuration of the schema (see Sample) to
+// the io.Writer. The header is written as a comment in the first line.
+func (s *Schema) WriteSample(writer io.Writer, header string) error {
+	return s.Sample().Write(writer, header)
+}
+
+// comment describes the option for Sample.
+func (opt *OptionSchema) comment() string {
+	var lines []string
+
+	if opt.Help != "" {
+		lines = append(lines, opt.Help)
+	}
+	lines = append(lines, "Type: "+opt.Type.String())
+	if opt.Required {
+		lines = append(lines, "Required.")
+	} else if opt.Default != "" {
+		lines = append(lines, "Default: "+opt.Default)
+	}
+	if opt.Range != nil {
+		lines = append(lines, fmt.Sprintf("Range: %v to %v", opt.Range.Min, opt.Range.Max))
+	}
+	if len(opt.Enum) > 0 {
+		lines = append(lines, "Allowed values: "+strings.Join(opt.Enum, ", "))
+	}
+	if opt.Pattern != nil {
+		lines = append(lines, "Pattern: "+opt.Pattern.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// SchemaOf returns the schema described by a struct (or pointer to struct).
+// Struct fields become sections and other fields become options of the
+// default section; fields of section structs become their options.
+//
+// The tag `conf:"name,required"` sets the name (the lower-cased field name if
+// empty) and whether the option is required; `conf:"-"` skips the field.
+// The tags `help:"..."` and `default:"..."` set the description and default.
+// Without a default tag, non-zero field values are used as default.
+//
+// Supported field types are string, int, int64, float64, bool, slices of
+// those and map[string]string.
+func SchemaOf(v interface{}) (*Schema, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("conf: SchemaOf expects a struct, got %T", v)
+	}
+
+	s := new(Schema)
+	def := SectionSchema{Name: DefaultSection}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, required, ok := fieldName(f)
+		if !ok {
+			continue
+		}
+
+		if f.Type.Kind() != reflect.Struct {
+			opt, err := optionOf(f, rv.Field(i), name, required)
+			if err != nil {
+				return nil, err
+			}
+			def.Options = append(def.Options, opt)
+			continue
+		}
+
+		ss := SectionSchema{Name: name, Help: f.Tag.Get("help"), Required: required}
+		for j := 0; j < f.Type.NumField(); j++ {
+			sf := f.Type.Field(j)
+			name, required, ok := fieldName(sf)
+			if !ok {
+				continue
+			}
+			opt, err := optionOf(sf, rv.Field(i).Field(j), name, required)
+			if err != nil {
+				return nil, err
+			}
+			ss.Options = append(ss.Options, opt)
+		}
+		s.Sections = append(s.Sections, ss)
+	}
+
+	if len(def.Options) > 0 {
+		s.Sections = append([]SectionSchema{def}, s.Sections...)
+	}
+
+	return s, nil
+}
+
+// fieldName returns the section or option name of a struct field, and
+// whether it is required. It returns false if the field is skipped.
+func fieldName(f reflect.StructField) (name string, required bool, ok bool) {
+	if f.PkgPath != "" { // unexported
+		return "", false, false
+	}
+
+	tag := f.Tag.Get("conf")
+	if tag == "-" {
+		return "", false, false
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	for _, p := range parts[1:] {
+		if p == "required" {
+			required = true
+		}
+	}
+
+	return name, required, true
+}
+
+var (
+	stringType = reflect.TypeOf("")
+	intType    = reflect.TypeOf(int(0))
+	int64Type  = reflect.TypeOf(int64(0))
+	floatType  = reflect.TypeOf(float64(0))
+	boolType   = reflect.TypeOf(false)
+
+	fieldTypes = map[reflect.Type]Type{
+		stringType:                            TypeString,
+		intType:                               TypeInt,
+		int64Type:                             TypeInt64,
+		floatType:                             TypeFloat64,
+		boolType:                              TypeBool,
+		reflect.SliceOf(stringType):           TypeStringList,
+		reflect.SliceOf(intType):              TypeIntList,
+		reflect.SliceOf(int64Type):            TypeInt64List,
+		reflect.SliceOf(floatType):            TypeFloat64List,
+		reflect.SliceOf(boolType):             TypeBoolList,
+		reflect.MapOf(stringType, stringType): TypeStringMap,
+	}
+)
+
+// optionOf returns the declaration of the option stored in a struct field.
+func optionOf(f reflect.StructField, v reflect.Value, name string, required bool) (opt OptionSchema, err error) {
+	t, ok := fieldTypes[f.Type]
+	if !ok {
+		return opt, fmt.Errorf("conf: unsupported type %s of field %s", f.Type, f.Name)
+	}
+
+	opt = OptionSchema{
+		Name:     name,
+		Help:     f.Tag.Get("help"),
+		Type:     t,
+		Required: required,
+	}
+
+	if d, ok := f.Tag.Lookup("default"); ok {
+		opt.Default = d
+	} else if !v.IsZero() {
+		opt.Default = formatValue(v)
+	}
+
+	return opt, nil
+}
+
+// formatValue formats a field value the way the getters parse it.
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ", ")
+
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			items = append(items, k.String()+"="+v.MapIndex(k).String())
+		}
+		sort.Strings(items)
+		return strings.Join(items, ", ")
+	}
+
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,63 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"testing"
+)
+
+type sampleConf struct {
+	Host   string `help:"Host name to listen on."`
+	Server struct {
+		Port int      `conf:"port,required" help:"TCP port."`
+		Mode string   `default:"fast"`
+		Tags []string `conf:"tags"`
+	} `help:"Server settings."`
+	ignored int
+}
+
+func TestSchemaOfSample(t *testing.T) {
+	v := sampleConf{Host: "localhost"}
+	v.Server.Tags = []string{"a", "b"}
+
+	s, err := SchemaOf(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := s.WriteSample(buf, "Sample"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Sample
+[default]
+# Host name to listen on.
+# Type: string
+# Default: localhost
+host=localhost
+
+# Server settings.
+[server]
+# TCP port.
+# Type: int
+# Required.
+port=
+# Type: string
+# Default: fast
+mode=fast
+# Type: string list
+# Default: a, b
+tags=a, b
+
+`
+	if buf.String() != expected {
+		t.Fatalf("WriteSample wrote:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	if _, err := SchemaOf(struct{ C chan int }{}); err == nil {
+		t.Fatal("SchemaOf accepted unsupported field type")
+	}
+}
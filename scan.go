@@ -0,0 +1,175 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// EventType is the kind of an Event returned by a Scanner.
+type EventType int
+
+const (
+	SectionEvent      EventType = iota // A section header, e.g. [section] or [section "subsection"].
+	OptionEvent                        // An option and its value, or a flag.
+	CommentEvent                       // A comment line.
+	ContinuationEvent                  // A continuation line of a multi-line value.
+)
+
+func (t EventType) String() string {
+	switch t {
+	case SectionEvent:
+		return "section"
+	case OptionEvent:
+		return "option"
+	case CommentEvent:
+		return "comment"
+	case ContinuationEvent:
+		return "continuation"
+	}
+
+	return "invalid event"
+}
+
+// Event is a line of a configuration file, as returned by a Scanner.
+type Event struct {
+	Type       EventType
+	Pos        Position
+	Section    string // Section name of a SectionEvent.
+	Subsection string // Quoted subsection name of a SectionEvent, if any.
+	Option     string // Option of an OptionEvent, or continued by a ContinuationEvent.
+	Value      string // Value of an OptionEvent, text of a ContinuationEvent or CommentEvent.
+	Flag       bool   // The OptionEvent is an option without value.
+	Raw        string // The line, without surrounding white space.
+}
+
+// Scanner reads a configuration file event by event, without storing it,
+// e.g. to process very large files. Read is built on it:
+//
+//	s := conf.NewScanner(reader)
+//	for s.Scan() {
+//		e := s.Event()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// Empty lines are skipped. Values are stripped of comments.
+type Scanner struct {
+	buf     *bufio.Reader
+	pos     Position
+	event   Event
+	err     error
+	done    bool
+	option  string // Option continued by continuation lines.
+	noValue bool
+}
+
+// NewScanner returns a Scanner reading from reader.
+func NewScanner(reader io.Reader) *Scanner {
+	return newScanner(reader, "")
+}
+
+// newScanner returns a Scanner recording positions in fname.
+func newScanner(reader io.Reader, fname string) *Scanner {
+	return &Scanner{buf: bufio.NewReader(reader), pos: Position{fname, 0}}
+}
+
+// SetAllowNoValue sets whether lines without '=' or ':' are options without
+// value, as with Config.SetAllowNoValue. Continuation lines must then be
+// indented.
+func (s *Scanner) SetAllowNoValue(allow bool) {
+	s.noValue = allow
+}
+
+// Scan advances the Scanner to the next event, which is then available
+// through Event. It returns false at the end of the input or on error.
+func (s *Scanner) Scan() bool {
+	for s.err == nil && !s.done {
+		l, err := s.buf.ReadString('\n') // parse line-by-line
+		if err != nil && err != io.EOF {
+			s.err = err
+			return false
+		}
+		indented := len(l) > 0 && (l[0] == ' ' || l[0] == '\t')
+		l = strings.TrimSpace(l)
+		s.pos.Line++
+		s.done = err == io.EOF
+
+		if len(l) == 0 { // empty line
+			continue
+		}
+
+		if s.event, s.err = s.parse(l, indented); s.err != nil {
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// Event returns the event read by the last call to Scan.
+func (s *Scanner) Event() Event {
+	return s.event
+}
+
+// Err returns the first error encountered by the Scanner: a ReadError for a
+// line that could not be parsed, or the error of the reader.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// parse parses the trimmed, non-empty line l.
+func (s *Scanner) parse(l string, indented bool) (e Event, err error) {
+	e = Event{Pos: s.pos, Raw: l}
+
+	// switch written for readability (not performance)
+	switch {
+	case l[0] == '#' || l[0] == ';': // comment
+		e.Type = CommentEvent
+		e.Value = strings.TrimSpace(l[1:])
+
+	case len(l) >= 3 && strings.ToLower(l[0:3]) == "rem": // comment (for windows users)
+		e.Type = CommentEvent
+		e.Value = strings.TrimSpace(l[3:])
+
+	case l[0] == '[' && l[len(l)-1] == ']': // new section
+		s.option = "" // reset multi-line value
+		e.Type = SectionEvent
+		e.Section = strings.TrimSpace(l[1 : len(l)-1])
+		if name, sub, ok := splitSubsection(e.Section); ok {
+			e.Section, e.Subsection = name, sub
+		}
+
+	default: // other alternatives
+		i := strings.IndexAny(l, "=:")
+		switch {
+		case i > 0: // option and value
+			s.option = strings.TrimSpace(l[0:i])
+			e.Type = OptionEvent
+			e.Option = s.option
+			e.Value = strings.TrimSpace(stripComments(l[i+1:]))
+
+		case s.noValue && !(indented && s.option != ""): // option without value
+			s.option = strings.TrimSpace(stripComments(l))
+			e.Type = OptionEvent
+			e.Option = s.option
+			e.Flag = true
+
+		case s.option != "": // continuation of multi-line value
+			e.Type = ContinuationEvent
+			e.Option = s.option
+			e.Value = strings.TrimSpace(stripComments(l))
+
+		default:
+			return e, ReadError{CouldNotParse, l}
+		}
+	}
+
+	return e, nil
+}
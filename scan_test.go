@@ -0,0 +1,46 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	const file = "# header\n[remote \"origin\"]\nurl = git://x ; comment\nfetch = a\n  b\n\n[mysqld]\nskip-networking\n"
+
+	s := NewScanner(strings.NewReader(file))
+	s.SetAllowNoValue(true)
+
+	var events []Event
+	for s.Scan() {
+		events = append(events, s.Event())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Event{
+		{CommentEvent, Position{"", 1}, "", "", "", "header", false, "# header"},
+		{SectionEvent, Position{"", 2}, "remote", "origin", "", "", false, `[remote "origin"]`},
+		{OptionEvent, Position{"", 3}, "", "", "url", "git://x", false, "url = git://x ; comment"},
+		{OptionEvent, Position{"", 4}, "", "", "fetch", "a", false, "fetch = a"},
+		{ContinuationEvent, Position{"", 5}, "", "", "fetch", "b", false, "b"},
+		{SectionEvent, Position{"", 7}, "mysqld", "", "", "", false, "[mysqld]"},
+		{OptionEvent, Position{"", 8}, "", "", "skip-networking", "", true, "skip-networking"},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Scanner returned %v, expected %v", events, expected)
+	}
+
+	s = NewScanner(strings.NewReader("[a]\nnot an option\n"))
+	for s.Scan() {
+	}
+	if err := s.Err(); !errors.Is(err, ErrCouldNotParse) {
+		t.Errorf("s.Err returned %v, expected ErrCouldNotParse", err)
+	}
+}
@@ -0,0 +1,351 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is the type of an option value declared in a Schema.
+// Each type corresponds to the Config getter used to read the value.
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeInt
+	TypeInt64
+	TypeFloat64
+	TypeBool
+	TypeStringList
+	TypeIntList
+	TypeInt64List
+	TypeFloat64List
+	TypeBoolList
+	TypeStringMap
+)
+
+var typeNames = []string{
+	TypeString:      "string",
+	TypeInt:         "int",
+	TypeInt64:       "int64",
+	TypeFloat64:     "float64",
+	TypeBool:        "bool",
+	TypeStringList:  "string list",
+	TypeIntList:     "int list",
+	TypeInt64List:   "int64 list",
+	TypeFloat64List: "float64 list",
+	TypeBoolList:    "bool list",
+	TypeStringMap:   "string map",
+}
+
+func (t Type) String() string {
+	if t < 0 || int(t) >= len(typeNames) {
+		return "Type(" + strconv.Itoa(int(t)) + ")"
+	}
+	return typeNames[t]
+}
+
+// isList reports whether values of type t are read with a list getter.
+func (t Type) isList() bool {
+	return t >= TypeStringList && t <= TypeBoolList
+}
+
+// Schema declares the sections and options a configuration is expected to
+// have. Use Validate to check a configuration against it.
+type Schema struct {
+	Sections    []SectionSchema
+	WarnUnknown bool // Report sections and options not in the schema as warnings.
+}
+
+// SectionSchema declares a section and its options.
+// Use DefaultSection as name to declare options outside of any section.
+type SectionSchema struct {
+	Name     string
+	Help     string
+	Required bool
+	Options  []OptionSchema
+}
+
+// OptionSchema declares an option. For list types, Range, Enum and Pattern
+// apply to every item of the list.
+type OptionSchema struct {
+	Name     string
+	Help     string
+	Type     Type
+	Required bool
+	Default  string
+	Range    *Range         // Allowed range of numeric values.
+	Enum     []string       // Allowed values.
+	Pattern  *regexp.Regexp // Pattern values must match.
+	Secret   bool           // The value is secret (see MarkSecrets).
+}
+
+// Range is an inclusive range of numeric values.
+type Range struct {
+	Min, Max float64
+}
+
+// Violation describes an option or section of a configuration which does not
+// conform to a Schema.
+type Violation struct {
+	Pos     Position
+	Section string
+	Option  string // Empty if the violation concerns the whole section.
+	Warning bool   // The violation is only a warning, e.g. an unknown option.
+	Message string
+}
+
+func (v Violation) Error() string {
+	msg := "[" + v.Section + "]"
+	if v.Option != "" {
+		msg += " " + v.Option
+	}
+	msg += ": " + v.Message
+	if v.Warning {
+		msg = "warning: " + msg
+	}
+	if v.Pos.IsValid() {
+		msg = v.Pos.String() + ": " + msg
+	}
+	return msg
+}
+
+// Validate checks the configuration against the schema and returns all
+// violations found, in schema order followed by unknown sections and options
+// if WarnUnknown is set. It returns nil if the configuration conforms.
+func (s *Schema) Validate(c *Config) (violations []Violation) {
+	for _, ss := range s.Sections {
+		section := c.sectionKey(ss.Name)
+		if !c.HasSection(section) {
+			if ss.Required {
+				violations = append(violations, Violation{
+					Section: section,
+					Message: "required section is missing",
+				})
+			}
+			continue
+		}
+
+		for _, opt := range ss.Options {
+			option := c.optionKey(opt.Name)
+			if _, err := c.RawString(section, option); err != nil {
+				if opt.Required {
+					violations = append(violations, Violation{
+						Pos:     c.Position(section, ""),
+						Section: section,
+						Option:  option,
+						Message: "required option is missing",
+					})
+				}
+				continue
+			}
+
+			if msg := opt.check(c, section, option); msg != "" {
+				violations = append(violations, Violation{
+					Pos:     c.Position(section, option),
+					Section: section,
+					Option:  option,
+					Message: msg,
+				})
+			}
+		}
+	}
+
+	if s.WarnUnknown {
+		violations = append(violations, s.unknown(c)...)
+	}
+
+	return violations
+}
+
+// ApplyDefaults adds every option declared in the schema that is missing from
+// the configuration, with its declared default value, creating sections as
+// needed. Required options and options without (or with an empty) default
+// are left missing, so that getters report them as not found.
+// It returns the number of options added.
+func (s *Schema) ApplyDefaults(c *Config) (n int) {
+	for _, ss := range s.Sections {
+		for _, opt := range ss.Options {
+			if opt.Required || opt.Default == "" {
+				continue
+			}
+			if _, err := c.RawString(ss.Name, opt.Name); err == nil {
+				continue
+			}
+			c.AddOption(ss.Name, opt.Name, opt.Default)
+			n++
+		}
+	}
+
+	return n
+}
+
+// MarkSecrets marks the options declared in the schema with Secret set as
+// secret in the configuration (see Config.SetSecret). Other options are left
+// as they are, so that declaring an option never makes its value public.
+func (s *Schema) MarkSecrets(c *Config) {
+	for _, ss := range s.Sections {
+		for _, opt := range ss.Options {
+			if opt.Secret {
+				c.SetSecret(ss.Name, opt.Name, true)
+			}
+		}
+	}
+}
+
+// unknown returns warnings for the sections and options of c not declared in
+// the schema.
+func (s *Schema) unknown(c *Config) (violations []Violation) {
+	sections := c.Sections()
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		key := c.sectionKey(section)
+		ss := s.section(section)
+		if ss == nil {
+			if section == DefaultSection && len(c.data[key]) == 0 {
+				continue // default section always exists
+			}
+			violations = append(violations, Violation{
+				Pos:     c.Position(section, ""),
+				Section: section,
+				Warning: true,
+				Message: "unknown section",
+			})
+			continue
+		}
+
+		options := make([]string, 0, len(c.data[key]))
+		for option := range c.data[key] {
+			options = append(options, option)
+		}
+		sort.Strings(options)
+
+		for _, option := range options {
+			if ss.option(option) == nil {
+				violations = append(violations, Violation{
+					Pos:     c.Position(section, option),
+					Section: section,
+					Option:  option,
+					Warning: true,
+					Message: "unknown option",
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// section returns the declaration of the named section, or nil.
+func (s *Schema) section(name string) *SectionSchema {
+	for i := range s.Sections {
+		if strings.EqualFold(s.Sections[i].Name, name) {
+			return &s.Sections[i]
+		}
+	}
+	return nil
+}
+
+// option returns the declaration of the named option, or nil.
+func (ss *SectionSchema) option(name string) *OptionSchema {
+	for i := range ss.Options {
+		if strings.EqualFold(ss.Options[i].Name, name) {
+			return &ss.Options[i]
+		}
+	}
+	return nil
+}
+
+// check checks the value of the option in c and returns a message
+// describing the problem, or the empty string if the value is valid.
+func (opt *OptionSchema) check(c *Config, section string, option string) string {
+	if opt.Type == TypeStringMap {
+		if _, err := c.StringMap(section, option); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+
+	var items []string
+	if opt.Type.isList() {
+		values, err := c.StringList(section, option)
+		if err != nil {
+			return err.Error()
+		}
+		items = values
+	} else {
+		value, err := c.String(section, option)
+		if err != nil {
+			return err.Error()
+		}
+		items = []string{value}
+	}
+
+	for _, item := range items {
+		if msg := opt.checkItem(c, item); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+// checkItem checks a single (list item) value.
+func (opt *OptionSchema) checkItem(c *Config, value string) string {
+	var num float64
+	var err error
+
+	switch opt.Type {
+	case TypeInt, TypeIntList:
+		var n int
+		n, err = strconv.Atoi(value)
+		num = float64(n)
+	case TypeInt64, TypeInt64List:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 64)
+		num = float64(n)
+	case TypeFloat64, TypeFloat64List:
+		num, err = strconv.ParseFloat(value, 64)
+	case TypeBool, TypeBoolList:
+		if _, ok := c.parseBool(value); !ok {
+			return fmt.Sprintf("could not parse bool value '%s'", value)
+		}
+	}
+	if err != nil {
+		return fmt.Sprintf("could not parse %s value '%s'", strings.TrimSuffix(opt.Type.String(), " list"), value)
+	}
+
+	if opt.Range != nil {
+		switch opt.Type {
+		case TypeInt, TypeIntList, TypeInt64, TypeInt64List, TypeFloat64, TypeFloat64List:
+			if num < opt.Range.Min || num > opt.Range.Max {
+				return fmt.Sprintf("value %s out of range [%v, %v]", value, opt.Range.Min, opt.Range.Max)
+			}
+		}
+	}
+
+	if len(opt.Enum) > 0 {
+		found := false
+		for _, e := range opt.Enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("value '%s' not one of %s", value, strings.Join(opt.Enum, ", "))
+		}
+	}
+
+	if opt.Pattern != nil && !opt.Pattern.MatchString(value) {
+		return fmt.Sprintf("value '%s' does not match %s", value, opt.Pattern)
+	}
+
+	return ""
+}
@@ -0,0 +1,87 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+const schemaConfFile = `
+[default]
+host = example.com
+prot = 443
+
+[server]
+port = 70000
+mode = fast
+tags = a, b1
+
+[extra]
+foo = bar
+`
+
+var testSchema = Schema{
+	Sections: []SectionSchema{
+		{Name: "default", Options: []OptionSchema{
+			{Name: "host", Required: true},
+			{Name: "port", Type: TypeInt, Default: "443"},
+			{Name: "timeout", Type: TypeInt},
+		}},
+		{Name: "server", Required: true, Options: []OptionSchema{
+			{Name: "port", Type: TypeInt, Range: &Range{1, 65535}},
+			{Name: "mode", Enum: []string{"fast", "safe"}},
+			{Name: "tags", Type: TypeStringList, Pattern: regexp.MustCompile(`^[a-z]+$`)},
+			{Name: "user", Required: true},
+		}},
+		{Name: "client", Required: true},
+	},
+	WarnUnknown: true,
+}
+
+func TestSchemaValidate(t *testing.T) {
+	c, err := ReadBytes([]byte(schemaConfFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"line 7: [server] port: value 70000 out of range [1, 65535]",
+		"line 9: [server] tags: value 'b1' does not match ^[a-z]+$",
+		"line 6: [server] user: required option is missing",
+		"[client]: required section is missing",
+		"line 4: warning: [default] prot: unknown option",
+		"line 11: warning: [extra]: unknown section",
+	}
+
+	violations := testSchema.Validate(c)
+	if len(violations) != len(expected) {
+		t.Fatalf("Validate returned %d violations, expected %d: %v", len(violations), len(expected), violations)
+	}
+	for i, v := range violations {
+		if v.Error() != expected[i] {
+			t.Errorf("%d. violation %q != %q", i, v.Error(), expected[i])
+		}
+	}
+}
+
+func TestSchemaApplyDefaults(t *testing.T) {
+	c, err := ReadBytes([]byte(schemaConfFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testSchema.ApplyDefaults(c); n != 1 {
+		t.Fatalf("ApplyDefaults added %d options, expected 1", n)
+	}
+	ans, err := c.Int("default", "port")
+	verify(t, 0, "c.Int", "default", "port", ans, 443, err)
+	if c.HasOption("server", "user") {
+		t.Fatal("ApplyDefaults added required option")
+	}
+	if _, err := c.Int("default", "timeout"); !errors.Is(err, ErrOptionNotFound) {
+		t.Fatalf("c.Int returned %v for option without default, expected ErrOptionNotFound", err)
+	}
+}
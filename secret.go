@@ -0,0 +1,140 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// RedactedValue replaces the values of secret options in redacted output.
+var RedactedValue = "******"
+
+// SecretResolver returns the secret value a reference stands for, e.g. the
+// content of the file "/run/secrets/db" for the reference of the value
+// "secret://file/run/secrets/db".
+type SecretResolver func(ref string) (string, error)
+
+// FileSecret is the SecretResolver of the "file" provider: it returns the
+// content of the file named ref, without trailing line break.
+func FileSecret(ref string) (string, error) {
+	data, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// ResolveSecrets returns a ValueTransformer resolving the secret references
+// in the values of secret options (see Config.IsSecret): "secret://provider/ref" is
+// resolved by the resolver of the provider, which is passed "/ref", and
+// "file:/path" by the resolver of the "file" provider. Values of other
+// options are never resolved, so that an untrusted configuration cannot read
+// files through them. Use it with Use, after Decrypter if values may be
+// encrypted:
+//
+//	c.Use(conf.ResolveSecrets(map[string]conf.SecretResolver{"file": conf.FileSecret}))
+func ResolveSecrets(resolvers map[string]SecretResolver) ValueTransformer {
+	return func(c *Config, section string, option string, value string) (string, error) {
+		if !c.IsSecret(section, option) {
+			return value, nil
+		}
+
+		var provider, ref string
+		switch {
+		case strings.HasPrefix(value, "secret://"):
+			provider, ref = value[len("secret://"):], ""
+			if i := strings.IndexByte(provider, '/'); i >= 0 {
+				provider, ref = provider[:i], provider[i:]
+			}
+		case strings.HasPrefix(value, "file:"):
+			provider, ref = "file", value[len("file:"):]
+		default:
+			return value, nil
+		}
+
+		resolver, ok := resolvers[provider]
+		if !ok {
+			return "", fmt.Errorf("conf: unknown secret provider '%s'", provider)
+		}
+		return resolver(ref)
+	}
+}
+
+// SetSecretPatterns replaces the patterns (see path.Match) of the names of
+// the options that are secret unless marked otherwise with SetSecret.
+// Patterns are matched case insensitively.
+func (c *Config) SetSecretPatterns(patterns ...string) {
+	c.patterns = make([]string, len(patterns))
+	for i, pattern := range patterns {
+		c.patterns[i] = strings.ToLower(pattern)
+	}
+}
+
+// SetSecret marks the given option in the section as secret or not,
+// regardless of the secret patterns. The mark applies to the name, whether
+// the option exists or not.
+func (c *Config) SetSecret(section string, option string, secret bool) {
+	section = c.sectionKey(section)
+	option = c.optionKey(option)
+
+	if _, ok := c.secrets[section]; !ok {
+		c.secrets[section] = make(map[string]bool)
+	}
+	c.secrets[section][option] = secret
+}
+
+// IsSecret checks if the given option in the section is secret: marked with
+// SetSecret, or else matching a secret pattern (see SetSecretPatterns).
+// The values of secret options are redacted by Redacted, by the fmt verbs and
+// in diffs.
+func (c *Config) IsSecret(section string, option string) bool {
+	if secret, ok := c.secrets[c.sectionKey(section)][c.optionKey(option)]; ok {
+		return secret
+	}
+
+	name := strings.ToLower(option)
+	for _, pattern := range c.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy of the configuration (see Clone) in which the
+// values of secret options are replaced by RedactedValue, e.g. to log it.
+func (c *Config) Redacted() *Config {
+	r := c.Clone()
+	for _, key := range r.sectionKeys() {
+		section := r.sectionName(key)
+		for _, option := range r.options[key] {
+			if !r.IsSecret(section, option) {
+				continue
+			}
+			r.data[key][option] = RedactedValue
+			for i := range r.values[key][option] {
+				r.values[key][option][i] = RedactedValue
+			}
+		}
+	}
+
+	return r
+}
+
+// Format implements fmt.Formatter: all verbs print the configuration file,
+// as written by Write, with the values of secret options redacted.
+func (c *Config) Format(f fmt.State, verb rune) {
+	f.Write(c.Redacted().WriteBytes(""))
+}
+
+// redact returns RedactedValue, or the empty string for an empty value.
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
@@ -0,0 +1,100 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "db")
+	if err := ioutil.WriteFile(fname, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ReadBytes([]byte("[db]\nuser = app\npassword = file:" + fname + "\ndsn = secret://file" + fname + "\nurl = file:/tmp/x\nmotd = secret://file/etc/motd\nconn = secret://vault/db\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans, _ := c.String("db", "password"); ans != "file:"+fname {
+		t.Error("secret resolved without ResolveSecrets")
+	}
+	c.Use(ResolveSecrets(map[string]SecretResolver{"file": FileSecret}))
+	c.SetSecret("db", "dsn", true)
+	c.SetSecret("db", "conn", true)
+
+	for i, e := range []struct{ option, value string }{
+		{"user", "app"},
+		{"password", "s3cr3t"},
+		{"dsn", "s3cr3t"},
+		{"url", "file:/tmp/x"},             // not secret
+		{"motd", "secret://file/etc/motd"}, // not secret
+	} {
+		ans, err := c.String("db", e.option)
+		verify(t, i, "c.String", "db", e.option, ans, e.value, err)
+	}
+	if _, err := c.String("db", "conn"); !errors.Is(err, ErrCouldNotTransform) {
+		t.Errorf("secret of unknown provider resolved: %v", err)
+	}
+
+	out := fmt.Sprint(c)
+	if strings.Contains(out, fname) || !strings.Contains(out, "password="+RedactedValue) || !strings.Contains(out, "user=app") {
+		t.Errorf("fmt.Sprint(c) did not redact secrets: %q", out)
+	}
+	if ans, _ := c.RawString("db", "password"); ans != "file:"+fname {
+		t.Error("c.Redacted modified the configuration")
+	}
+
+	s := &Schema{Sections: []SectionSchema{{Name: "db", Options: []OptionSchema{{Name: "password"}, {Name: "user", Secret: true}}}}}
+	s.MarkSecrets(c)
+	if !c.IsSecret("db", "password") || !c.IsSecret("db", "user") {
+		t.Error("s.MarkSecrets did not mark user as secret or unmarked password")
+	}
+	c.SetSecret("db", "user", false)
+
+	b := c.Clone()
+	b.AddOption("db", "dsn", "x")
+	changes := Diff(c, b)
+	if len(changes) != 1 || !changes[0].Secret || strings.Contains(changes[0].String(), fname) {
+		t.Errorf("Diff did not redact secret change: %v", changes)
+	}
+}
+
+func TestEncryptedSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "db")
+	if err := ioutil.WriteFile(fname, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key := bytes.Repeat([]byte{7}, 32)
+
+	c, err := ReadBytes([]byte("[db]\npassword = file:" + fname + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Encrypt(StaticKey(key), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Use(Decrypter(StaticKey(key)))
+	c.Use(ResolveSecrets(map[string]SecretResolver{"file": FileSecret}))
+	ans, err := c.String("db", "password")
+	verify(t, 0, "c.String", "db", "password", ans, "s3cr3t", err)
+}
@@ -0,0 +1,422 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ReadTOML reads a TOML document and returns a new configuration
+// representation. Tables become sections (dotted table names and dotted keys
+// give sections named "table.subtable") and top-level keys become options of
+// the default section. Strings are unescaped, integers are converted to
+// decimal, other scalars are stored as written and arrays are stored in the
+// multi-line form understood by StringList.
+// Arrays of tables, inline tables and nested arrays are not supported.
+func ReadTOML(reader io.Reader) (c *Config, err error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	c = New()
+	p := tomlParser{c: c, s: string(data), line: 1, section: DefaultSection}
+	if err = p.parse(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// tomlParser reads a TOML document into a configuration.
+type tomlParser struct {
+	c       *Config
+	s       string
+	i       int    // Offset of the next byte to read.
+	line    int    // Current line number.
+	section string // Current section.
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return nil
+		}
+
+		if p.peek() == '[' {
+			if strings.HasPrefix(p.s[p.i:], "[[") {
+				return p.error()
+			}
+			p.i++
+			keys, err := p.parseKey()
+			if err != nil {
+				return err
+			}
+			if p.peek() != ']' {
+				return p.error()
+			}
+			p.i++
+			p.section = strings.Join(keys, ".")
+			p.c.AddSection(p.section)
+			p.c.setPosition(p.section, "", Position{Line: p.line})
+		} else {
+			keys, err := p.parseKey()
+			if err != nil {
+				return err
+			}
+			if p.peek() != '=' {
+				return p.error()
+			}
+			p.i++
+			p.skipSpace(false)
+
+			line := p.line
+			value, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+
+			section := p.section
+			if len(keys) > 1 {
+				sub := strings.Join(keys[:len(keys)-1], ".")
+				if section == DefaultSection {
+					section = sub
+				} else {
+					section += "." + sub
+				}
+			}
+			option := keys[len(keys)-1]
+			p.c.AddOption(section, option, value)
+			p.c.setPosition(section, option, Position{Line: line})
+		}
+
+		// only a comment may follow on the line
+		p.skipSpace(false)
+		if !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+			return p.error()
+		}
+	}
+}
+
+// parseKey parses a dotted key, returning its parts.
+func (p *tomlParser) parseKey() (keys []string, err error) {
+	for {
+		p.skipSpace(false)
+
+		var key string
+		switch p.peek() {
+		case '"':
+			if key, err = p.parseBasicString(); err != nil {
+				return nil, err
+			}
+		case '\'':
+			if key, err = p.parseLiteralString(); err != nil {
+				return nil, err
+			}
+		default:
+			j := p.i
+			for j < len(p.s) && isBareKeyChar(p.s[j]) {
+				j++
+			}
+			if j == p.i {
+				return nil, p.error()
+			}
+			key = p.s[p.i:j]
+			p.i = j
+		}
+		keys = append(keys, key)
+
+		p.skipSpace(false)
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.i++
+	}
+}
+
+// parseValue parses a value, returning its string form.
+func (p *tomlParser) parseValue() (string, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		return p.parseBasicString()
+	case c == '\'':
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return "", p.error() // inline tables are not supported
+	}
+
+	j := p.i
+	for j < len(p.s) && !strings.ContainsRune(" \t\r\n,]#", rune(p.s[j])) {
+		j++
+	}
+	// local date-times may separate date and time with a space
+	if j+1 < len(p.s) && p.s[j] == ' ' && strings.Count(p.s[p.i:j], "-") == 2 && isDigit(p.s[j+1]) {
+		for j++; j < len(p.s) && !strings.ContainsRune(" \t\r\n,]#", rune(p.s[j])); j++ {
+		}
+	}
+	tok := p.s[p.i:j]
+
+	switch {
+	case tok == "true" || tok == "false":
+	case tok == "":
+		return "", p.error()
+	default:
+		if n, err := strconv.ParseInt(tok, 0, 64); err == nil {
+			tok = strconv.FormatInt(n, 10)
+		} else if _, err := strconv.ParseFloat(strings.Replace(tok, "_", "", -1), 64); err == nil {
+			tok = strings.Replace(tok, "_", "", -1)
+		} else if !isDigit(tok[0]) { // not a date or time either
+			return "", p.error()
+		}
+	}
+	p.i = j
+
+	return tok, nil
+}
+
+// parseArray parses an array of scalars.
+func (p *tomlParser) parseArray() (string, error) {
+	p.i++ // [
+
+	var items []string
+	for {
+		p.skipSpace(true)
+		if p.peek() == ']' {
+			p.i++
+			return joinList(items), nil
+		}
+		if p.peek() == '[' {
+			return "", p.error() // nested arrays are not supported
+		}
+
+		item, err := p.parseValue()
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
+
+		p.skipSpace(true)
+		switch p.peek() {
+		case ',':
+			p.i++
+		case ']':
+		default:
+			return "", p.error()
+		}
+	}
+}
+
+// parseBasicString parses a (multi-line) basic string, handling escapes.
+func (p *tomlParser) parseBasicString() (string, error) {
+	multi := strings.HasPrefix(p.s[p.i:], `"""`)
+	if multi {
+		p.i += 3
+		p.skipNewline()
+	} else {
+		p.i++
+	}
+
+	var buf []byte
+	for {
+		if p.eof() {
+			return "", p.error()
+		}
+
+		switch c := p.s[p.i]; {
+		case multi && strings.HasPrefix(p.s[p.i:], `"""`):
+			// up to two quotes may precede the closing delimiter
+			for strings.HasPrefix(p.s[p.i+1:], `"""`) {
+				buf = append(buf, '"')
+				p.i++
+			}
+			p.i += 3
+			return string(buf), nil
+
+		case c == '"' && !multi:
+			p.i++
+			return string(buf), nil
+
+		case c == '\n' && !multi:
+			return "", p.error()
+
+		case c == '\\':
+			p.i++
+			if p.eof() {
+				return "", p.error()
+			}
+			if multi && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+				// line ending backslash trims following white space
+				j := p.i
+				for j < len(p.s) && (p.s[j] == ' ' || p.s[j] == '\t') {
+					j++
+				}
+				if j < len(p.s) && (p.s[j] == '\n' || p.s[j] == '\r') {
+					// skip white space and newlines only: '#' is content here
+					for p.i = j; !p.eof() && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0; p.i++ {
+						if p.s[p.i] == '\n' {
+							p.line++
+						}
+					}
+					continue
+				}
+			}
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, string(r)...)
+
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			buf = append(buf, c)
+			p.i++
+		}
+	}
+}
+
+// parseEscape parses the escape sequence following a backslash.
+func (p *tomlParser) parseEscape() (rune, error) {
+	c := p.s[p.i]
+	p.i++
+
+	switch c {
+	case 'b':
+		return '\b', nil
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case 'e':
+		return '\x1b', nil
+	case '"', '\\':
+		return rune(c), nil
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.i+n > len(p.s) {
+			return 0, p.error()
+		}
+		v, err := strconv.ParseUint(p.s[p.i:p.i+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(v)) {
+			return 0, p.error()
+		}
+		p.i += n
+		return rune(v), nil
+	}
+
+	return 0, p.error()
+}
+
+// parseLiteralString parses a (multi-line) literal string.
+func (p *tomlParser) parseLiteralString() (string, error) {
+	delim := "'"
+	if strings.HasPrefix(p.s[p.i:], "'''") {
+		delim = "'''"
+	}
+	p.i += len(delim)
+	if delim == "'''" {
+		p.skipNewline()
+	}
+
+	j := strings.Index(p.s[p.i:], delim)
+	if j < 0 {
+		return "", p.error()
+	}
+	value := p.s[p.i : p.i+j]
+	if delim == "'" && strings.IndexByte(value, '\n') >= 0 {
+		return "", p.error()
+	}
+	// up to two quotes may precede the closing delimiter
+	for delim == "'''" && strings.HasPrefix(p.s[p.i+j+1:], delim) {
+		j++
+		value = p.s[p.i : p.i+j]
+	}
+	p.line += strings.Count(value, "\n")
+	p.i += j + len(delim)
+
+	return value, nil
+}
+
+// skipSpace skips white space and comments, and newlines if newlines is set.
+func (p *tomlParser) skipSpace(newlines bool) {
+	for !p.eof() {
+		switch p.s[p.i] {
+		case ' ', '\t':
+			p.i++
+		case '\r', '\n':
+			if !newlines {
+				return
+			}
+			if p.s[p.i] == '\n' {
+				p.line++
+			}
+			p.i++
+		case '#':
+			for !p.eof() && p.s[p.i] != '\n' {
+				p.i++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipNewline skips a newline directly following the opening delimiter of a
+// multi-line string.
+func (p *tomlParser) skipNewline() {
+	if strings.HasPrefix(p.s[p.i:], "\r\n") {
+		p.i++
+	}
+	if strings.HasPrefix(p.s[p.i:], "\n") {
+		p.i++
+		p.line++
+	}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.i >= len(p.s)
+}
+
+// peek returns the next byte, or 0 at the end of the document.
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+// error returns a ReadError for the current line.
+func (p *tomlParser) error() error {
+	start := strings.LastIndexByte(p.s[:p.i], '\n') + 1
+	end := strings.IndexByte(p.s[p.i:], '\n')
+	if end < 0 {
+		end = len(p.s)
+	} else {
+		end += p.i
+	}
+	return ReadError{CouldNotParse, strings.TrimSpace(p.s[start:end])}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
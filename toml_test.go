@@ -0,0 +1,73 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"strings"
+	"testing"
+)
+
+const tomlConfFile = `
+# Comment
+host = "example.com"
+port = 0x1BB # 443
+
+[service-1]
+active = true
+ratio = 1_000.5
+list = [ 1, 2,
+	3, ]
+names = ['one,one', "two\tthree"]
+description = """
+first line
+second \
+  line"""
+server.port = 8080
+`
+
+func TestReadTOML(t *testing.T) {
+	c, err := ReadTOML(strings.NewReader(tomlConfFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkFormatSet(t, c)
+
+	ans, err := c.String("service-1", "description")
+	verify(t, 0, "c.String", "service-1", "description", ans, "first line\nsecond line", err)
+	port, err := c.Int("service-1.server", "port")
+	verify(t, 1, "c.Int", "service-1.server", "port", port, 8080, err)
+	if p := c.Position("service-1", "list"); p.Line != 9 {
+		t.Fatalf("c.Position(\"service-1\", \"list\") = %v, expected line 9", p)
+	}
+
+	c, err = ReadTOML(strings.NewReader("a = \"\"\"foo \\\n   # text\"\"\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ans, err = c.String("", "a")
+	verify(t, 2, "c.String", "", "a", ans, "foo # text", err)
+
+	for _, bad := range []string{"a = ", "[[a]]", "a = {b = 1}", `a = "x`, "a = 1 b"} {
+		if _, err := ReadTOML(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadTOML(%q) returned no error", bad)
+		}
+	}
+}
+
+// checkFormatSet checks the values shared by the format tests.
+func checkFormatSet(t *testing.T, c *Config) {
+	host, err := c.String("", "host")
+	verify(t, 0, "c.String", "", "host", host, "example.com", err)
+	port, err := c.Int("", "port")
+	verify(t, 1, "c.Int", "", "port", port, 443, err)
+	active, err := c.Bool("service-1", "active")
+	verify(t, 2, "c.Bool", "service-1", "active", active, true, err)
+	ratio, err := c.Float64("service-1", "ratio")
+	verify(t, 3, "c.Float64", "service-1", "ratio", ratio, 1000.5, err)
+	list, err := c.IntList("service-1", "list")
+	verifyList(t, 4, "c.IntList", "service-1", "list", list, []int{1, 2, 3}, err)
+	names, err := c.StringList("service-1", "names")
+	verifyList(t, 5, "c.StringList", "service-1", "names", names, []string{"one,one", "two\tthree"}, err)
+}
@@ -0,0 +1,70 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUse(t *testing.T) {
+	c, err := ReadBytes([]byte("host = example.com\n[web]\nurl = http://%(host)s:%(port)s/\nport = 80\nhome = ${CONF_TEST_HOME}/www\nloop = %(loop)s\nbad = x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("CONF_TEST_HOME", "/srv")
+	defer os.Unsetenv("CONF_TEST_HOME")
+
+	c.Use(Unfold)
+	c.Use(ExpandEnv)
+	c.Use(func(c *Config, section, option, value string) (string, error) {
+		if value == "x" {
+			return "", errors.New("bad value")
+		}
+		return strings.TrimSuffix(value, "/"), nil
+	})
+
+	ans, err := c.String("web", "url")
+	verify(t, 0, "c.String", "web", "url", ans, "http://example.com:80", err)
+	ans, err = c.String("web", "home")
+	verify(t, 1, "c.String", "web", "home", ans, "/srv/www", err)
+	list, err := c.StringList("web", "home")
+	verifyList(t, 2, "c.StringList", "web", "home", list, []string{"/srv/www"}, err)
+
+	_, err = c.String("web", "loop")
+	if e, ok := err.(GetError); !ok || e.Reason != MaxDepthReached {
+		t.Errorf("unfolding cycle returned %v", err)
+	}
+	_, err = c.Int("web", "bad")
+	if e, ok := err.(GetError); !ok || e.Reason != CouldNotTransform || e.Option != "bad" || e.Err.Error() != "bad value" {
+		t.Errorf("failed transformer returned %#v", err)
+	}
+
+	c.SetTransformer(nil)
+	if ans, _ := c.String("web", "url"); ans != "http://%(host)s:%(port)s/" {
+		t.Errorf("SetTransformer(nil) left transformers: %q", ans)
+	}
+}
+
+func TestUseViewAndClone(t *testing.T) {
+	c, err := ReadBytes([]byte("[server]\nhost = example.com\n[server.http]\nurl = http://%(host)s/\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Use(Unfold)
+
+	// transformers read the view, not the configuration they were added to
+	ans, err := c.Sub("server").String("http", "url")
+	verify(t, 0, "c.Sub.String", "http", "url", ans, "http://example.com/", err)
+
+	// and the clone, not the original
+	r := c.Clone()
+	r.AddOption("server", "host", "example.org")
+	ans, err = r.String("server.http", "url")
+	verify(t, 1, "r.String", "server.http", "url", ans, "http://example.org/", err)
+	ans, err = c.String("server.http", "url")
+	verify(t, 2, "c.String", "server.http", "url", ans, "http://example.com/", err)
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"strings"
 )
 
 // WriteFile saves the configuration representation to a file.
@@ -35,6 +36,10 @@ func (c *Config) WriteBytes(header string) (config []byte) {
 }
 
 // Writes the configuration file to the io.Writer.
+// Sections and options are written in the order they were added, each
+// preceded by its comment (see SetComment). Multi-valued options are
+// written once per value and flags without "=". If options without value are
+// allowed (see SetAllowNoValue), continuation lines are indented.
 func (c *Config) Write(writer io.Writer, header string) (err error) {
 	buf := bytes.NewBuffer(nil)
 
@@ -44,17 +49,40 @@ func (c *Config) Write(writer io.Writer, header string) (err error) {
 		}
 	}
 
-	for section, sectionmap := range c.data {
-		if section == DefaultSection && len(sectionmap) == 0 {
+	for _, section := range c.sectionKeys() {
+		sectionmap := c.data[section]
+		if section == c.sectionKey(DefaultSection) && len(sectionmap) == 0 {
 			continue // skip default section if empty
 		}
-		if _, err = buf.WriteString("[" + section + "]\n"); err != nil {
+		if err = writeComment(buf, c.comments[section][""]); err != nil {
 			return err
 		}
-		for option, value := range sectionmap {
-			if _, err = buf.WriteString(option + "=" + value + "\n"); err != nil {
+		if _, err = buf.WriteString("[" + c.sectionHeader(section) + "]\n"); err != nil {
+			return err
+		}
+		for _, option := range c.options[section] {
+			if err = writeComment(buf, c.comments[section][option]); err != nil {
 				return err
 			}
+			values, ok := c.values[section][option]
+			if !ok {
+				values = []string{sectionmap[option]}
+			}
+			if c.flags[section][option] {
+				values = []string{}
+				if _, err = buf.WriteString(c.optionName(section, option) + "\n"); err != nil {
+					return err
+				}
+			}
+			for _, value := range values {
+				if c.noValue {
+					// unindented continuation lines would be read as flags
+					value = strings.Replace(value, "\n", "\n\t", -1)
+				}
+				if _, err = buf.WriteString(c.optionName(section, option) + "=" + value + "\n"); err != nil {
+					return err
+				}
+			}
 		}
 		if _, err = buf.WriteString("\n"); err != nil {
 			return err
@@ -65,3 +93,31 @@ func (c *Config) Write(writer io.Writer, header string) (err error) {
 
 	return nil
 }
+
+// writeComment writes each line of comment prefixed with "# ".
+func writeComment(buf *bytes.Buffer, comment string) (err error) {
+	if comment == "" {
+		return nil
+	}
+	for _, l := range strings.Split(comment, "\n") {
+		if _, err = buf.WriteString(strings.TrimRight("# "+l, " ") + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sectionHeader returns the name of the section stored under key as written
+// between brackets. Subsections are written as git-style quoted subsection
+// names.
+func (c *Config) sectionHeader(key string) string {
+	name := c.sectionName(key)
+	if !c.subsections[key] || len(name) < len(key) {
+		return name
+	}
+
+	i := strings.IndexByte(name, '.')
+	sub := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name[i+1:])
+
+	return name[:i] + ` "` + sub + `"`
+}
@@ -0,0 +1,370 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ReadYAML reads a YAML document and returns a new configuration
+// representation. Top-level mappings become sections (nested mappings give
+// sections named "section.subsection") and other top-level keys become
+// options of the default section. Scalars are unquoted, null and ~ are
+// stored as the empty string and sequences (block or flow) are stored in the
+// multi-line form understood by StringList.
+// Only this block mapping subset of YAML is supported: anchors, tags,
+// sequences of mappings and multiple documents are not.
+func ReadYAML(reader io.Reader) (c *Config, err error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	c = New()
+	p := yamlParser{c: c, lines: strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")}
+	p.skip()
+	if p.i < len(p.lines) && strings.TrimSpace(p.lines[p.i]) == "---" {
+		p.i++
+		p.skip()
+	}
+	if p.i < len(p.lines) {
+		if err = p.parseMapping(p.indent(), DefaultSection, ""); err != nil {
+			return nil, err
+		}
+	}
+	if p.skip(); p.i < len(p.lines) {
+		return nil, ReadError{CouldNotParse, strings.TrimSpace(p.lines[p.i])}
+	}
+
+	return c, nil
+}
+
+// yamlParser reads a YAML document into a configuration, line by line.
+type yamlParser struct {
+	c     *Config
+	lines []string
+	i     int // Index of the current line.
+}
+
+// parseMapping parses the block mapping at the given indentation. Scalar
+// values are added to section, and nested mappings become sections named
+// prefix + key.
+func (p *yamlParser) parseMapping(indent int, section string, prefix string) error {
+	for p.skip(); p.i < len(p.lines) && p.indent() >= indent; p.skip() {
+		l := p.lines[p.i]
+		if p.indent() > indent {
+			return p.error()
+		}
+
+		key, rest, ok := splitYAMLKey(strings.TrimSpace(l))
+		if !ok {
+			return p.error()
+		}
+		pos := Position{Line: p.i + 1}
+		p.i++
+
+		switch {
+		case rest == "":
+			// the value is a nested block, if any
+			p.skip()
+			next := -1
+			if p.i < len(p.lines) {
+				next = p.indent()
+			}
+			item := p.i < len(p.lines) && strings.HasPrefix(strings.TrimSpace(p.lines[p.i]), "-")
+			switch {
+			case item && next >= indent:
+				items, err := p.parseSequence(next)
+				if err != nil {
+					return err
+				}
+				p.c.AddOption(section, key, joinList(items))
+				p.c.setPosition(section, key, pos)
+
+			case next > indent:
+				p.c.AddSection(prefix + key)
+				p.c.setPosition(prefix+key, "", pos)
+				if err := p.parseMapping(next, prefix+key, prefix+key+"."); err != nil {
+					return err
+				}
+
+			default:
+				p.c.AddOption(section, key, "")
+				p.c.setPosition(section, key, pos)
+			}
+
+		case rest[0] == '|' || rest[0] == '>':
+			p.c.AddOption(section, key, p.parseBlockScalar(indent, rest))
+			p.c.setPosition(section, key, pos)
+
+		default:
+			value, err := parseYAMLValue(rest)
+			if err != nil {
+				p.i--
+				return p.error()
+			}
+			p.c.AddOption(section, key, value)
+			p.c.setPosition(section, key, pos)
+		}
+	}
+
+	return nil
+}
+
+// parseSequence parses a block sequence of scalars at the given indentation.
+func (p *yamlParser) parseSequence(indent int) (items []string, err error) {
+	for p.skip(); p.i < len(p.lines) && p.indent() == indent; p.skip() {
+		l := strings.TrimSpace(p.lines[p.i])
+		if l != "-" && !strings.HasPrefix(l, "- ") {
+			break
+		}
+
+		item, err := parseYAMLScalar(strings.TrimSpace(l[1:]))
+		if err != nil {
+			return nil, p.error()
+		}
+		items = append(items, item)
+		p.i++
+	}
+
+	return items, nil
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) block scalar whose
+// lines are indented more than indent. The final line break is not kept.
+func (p *yamlParser) parseBlockScalar(indent int, header string) string {
+	var lines []string
+	blockIndent := -1
+	for ; p.i < len(p.lines); p.i++ {
+		l := p.lines[p.i]
+		if strings.TrimSpace(l) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " "))
+		if blockIndent < 0 {
+			blockIndent = n
+		}
+		if n <= indent || n < blockIndent {
+			break
+		}
+		lines = append(lines, l[blockIndent:])
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if header[0] == '|' {
+		return strings.Join(lines, "\n")
+	}
+
+	// folded: lines are joined by spaces, empty lines become line breaks
+	value := ""
+	for i, l := range lines {
+		switch {
+		case l == "":
+			value += "\n"
+		case i > 0 && lines[i-1] != "":
+			value += " " + l
+		default:
+			value += l
+		}
+	}
+	return value
+}
+
+// skip skips empty lines and comment lines.
+func (p *yamlParser) skip() {
+	for p.i < len(p.lines) {
+		l := strings.TrimSpace(p.lines[p.i])
+		if l != "" && l[0] != '#' {
+			return
+		}
+		p.i++
+	}
+}
+
+// indent returns the indentation of the current line.
+func (p *yamlParser) indent() int {
+	l := p.lines[p.i]
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// error returns a ReadError for the current line.
+func (p *yamlParser) error() error {
+	return ReadError{CouldNotParse, strings.TrimSpace(p.lines[p.i])}
+}
+
+// splitYAMLKey splits a mapping entry into its key and the rest of the line.
+func splitYAMLKey(l string) (key string, rest string, ok bool) {
+	if l == "" || l[0] == '-' && (len(l) == 1 || l[1] == ' ') {
+		return "", "", false // sequence entry
+	}
+
+	end := 0
+	if l[0] == '"' || l[0] == '\'' {
+		end = quoteEnd(l)
+		if end < 0 {
+			return "", "", false
+		}
+	}
+	// the key ends at the first colon followed by white space
+	i := -1
+	for j := end; j < len(l) && i < 0; j++ {
+		if l[j] == ':' && (j+1 == len(l) || l[j+1] == ' ' || l[j+1] == '\t') {
+			i = j
+		}
+	}
+	if i < 0 {
+		return "", "", false
+	}
+
+	key, err := parseYAMLScalar(strings.TrimSpace(l[:i]))
+	if err != nil || key == "" {
+		return "", "", false
+	}
+	rest = strings.TrimSpace(stripYAMLComment(l[i+1:]))
+
+	return key, rest, true
+}
+
+// parseYAMLValue parses a scalar or flow sequence.
+func parseYAMLValue(s string) (string, error) {
+	s = strings.TrimSpace(stripYAMLComment(s))
+	if s == "" || s[0] != '[' {
+		return parseYAMLScalar(s)
+	}
+
+	if s[len(s)-1] != ']' {
+		return "", ReadError{CouldNotParse, s}
+	}
+	var items []string
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	for inner != "" {
+		end := strings.IndexByte(inner, ',')
+		if inner[0] == '"' || inner[0] == '\'' {
+			q := quoteEnd(inner)
+			if q < 0 {
+				return "", ReadError{CouldNotParse, s}
+			}
+			end = strings.IndexByte(inner[q:], ',')
+			if end >= 0 {
+				end += q
+			}
+		}
+		if end < 0 {
+			end = len(inner)
+		}
+		item, err := parseYAMLScalar(strings.TrimSpace(inner[:end]))
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
+		if end == len(inner) {
+			break
+		}
+		inner = strings.TrimSpace(inner[end+1:])
+	}
+
+	return joinList(items), nil
+}
+
+// parseYAMLScalar parses a plain, single-quoted or double-quoted scalar.
+func parseYAMLScalar(s string) (string, error) {
+	s = strings.TrimSpace(stripYAMLComment(s))
+
+	switch {
+	case s == "~" || s == "null" || s == "Null" || s == "NULL":
+		return "", nil
+
+	case strings.HasPrefix(s, "\""):
+		if quoteEnd(s) != len(s) {
+			return "", ReadError{CouldNotParse, s}
+		}
+		return unquoteYAML(s)
+
+	case strings.HasPrefix(s, "'"):
+		if quoteEnd(s) != len(s) {
+			return "", ReadError{CouldNotParse, s}
+		}
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	}
+
+	return s, nil
+}
+
+// yamlEscapes maps the single-character escapes of double-quoted scalars.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v",
+	'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': `"`, '/': "/", '\\': `\`,
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// unquoteYAML unquotes the double-quoted scalar s, replacing the YAML escape
+// sequences.
+func unquoteYAML(s string) (string, error) {
+	buf := make([]byte, 0, len(s))
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] != '\\' {
+			buf = append(buf, s[i])
+			continue
+		}
+
+		i++
+		if e, ok := yamlEscapes[s[i]]; ok {
+			buf = append(buf, e...)
+			continue
+		}
+		n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+		if n == 0 || i+n >= len(s) {
+			return "", ReadError{CouldNotParse, s}
+		}
+		r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+		if err != nil || r > utf8.MaxRune {
+			return "", ReadError{CouldNotParse, s}
+		}
+		buf = append(buf, string(rune(r))...)
+		i += n
+	}
+
+	return string(buf), nil
+}
+
+// quoteEnd returns the offset just past the quoted scalar at the start of s,
+// or -1 if it is not terminated.
+func quoteEnd(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++ // escaped single quote
+		case s[i] == q:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment removes a comment (" #") outside of quotes.
+func stripYAMLComment(s string) string {
+	start := 0
+	if t := strings.TrimLeft(s, " \t"); t != "" && (t[0] == '"' || t[0] == '\'') {
+		if end := quoteEnd(t); end >= 0 {
+			start = len(s) - len(t) + end
+		}
+	}
+	if i := strings.Index(s[start:], " #"); i >= 0 {
+		return s[:start+i]
+	}
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"strings"
+	"testing"
+)
+
+const yamlConfFile = `---
+# Comment
+host: example.com
+port: 443 # comment
+
+service-1:
+  active: yes
+  ratio: 1000.5
+  list:
+    - 1
+    - 2
+    - 3
+  names: ['one,one', "two\tthree"]
+  description: >
+    first line
+    second line
+
+    third
+  server:
+    port: "8080"
+`
+
+func TestReadYAML(t *testing.T) {
+	c, err := ReadYAML(strings.NewReader(yamlConfFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkFormatSet(t, c)
+
+	ans, err := c.String("service-1", "description")
+	verify(t, 0, "c.String", "service-1", "description", ans, "first line second line\nthird", err)
+	port, err := c.Int("service-1.server", "port")
+	verify(t, 1, "c.Int", "service-1.server", "port", port, 8080, err)
+	if p := c.Position("service-1", "list"); p.Line != 9 {
+		t.Fatalf("c.Position(\"service-1\", \"list\") = %v, expected line 9", p)
+	}
+
+	for i, e := range []struct{ scalar, value string }{
+		{`"a\/b\\c"`, `a/b\c`},
+		{`"\e[0m\x41\u00e9\U0001F600"`, "\x1b[0mA\u00e9\U0001F600"},
+		{`"tab\	end\_"`, "tab\tend\u00a0"},
+	} {
+		c, err := ReadYAML(strings.NewReader("a: " + e.scalar))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ans, err := c.String("", "a")
+		verify(t, 2+i, "c.String", "", "a", ans, e.value, err)
+	}
+
+	for _, bad := range []string{"a: b\n  c: d", "- a", "a: [b", `a: "b`, `a: "\q"`, `a: "\u12"`} {
+		if _, err := ReadYAML(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadYAML(%q) returned no error", bad)
+		}
+	}
+}